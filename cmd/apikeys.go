@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/nodewee/llm-caller/pkg/llm"
+	"github.com/nodewee/llm-caller/pkg/templates"
+	"github.com/nodewee/llm-caller/pkg/utils"
+)
+
+// API key strategies for secret file entries holding several keys
+const (
+	keyStrategyFailover   = "failover"
+	keyStrategyRoundRobin = "round-robin"
+)
+
+// keyIndexFile stores the next round-robin position per secret file entry
+const keyIndexFile = "key-index.json"
+
+// apiKeyValues holds the keys of a secret file entry, written as a string or an array of strings
+type apiKeyValues []string
+
+// UnmarshalJSON accepts a single key or an array of keys
+func (v *apiKeyValues) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*v = apiKeyValues{single}
+		return nil
+	}
+
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return fmt.Errorf("API key values must be a string or an array of strings")
+	}
+	*v = multiple
+	return nil
+}
+
+// MarshalJSON writes a single key as a string and several keys as an array
+func (v apiKeyValues) MarshalJSON() ([]byte, error) {
+	if len(v) == 1 {
+		return json.Marshal(v[0])
+	}
+	return json.Marshal([]string(v))
+}
+
+// validateKeyStrategy checks the --key-strategy flag value
+func validateKeyStrategy(strategy string) error {
+	switch strategy {
+	case keyStrategyFailover, keyStrategyRoundRobin:
+		return nil
+	default:
+		return fmt.Errorf("invalid --key-strategy '%s', expected '%s' or '%s'", strategy, keyStrategyRoundRobin, keyStrategyFailover)
+	}
+}
+
+// selectAPIKeys orders the candidate keys according to the strategy
+// Round-robin returns the next key of the entry for this invocation, failover returns all keys in order
+func selectAPIKeys(keys []string, entry, strategy string) ([]string, error) {
+	if len(keys) <= 1 || strategy != keyStrategyRoundRobin {
+		return keys, nil
+	}
+
+	index, err := nextKeyIndex(entry, len(keys))
+	if err != nil {
+		return nil, err
+	}
+	return keys[index : index+1], nil
+}
+
+// nextKeyIndex returns the round-robin position for a secret file entry and advances it
+// The position is read and advanced under the index file lock, so concurrent invocations get different keys
+func nextKeyIndex(entry string, count int) (int, error) {
+	configDir, err := utils.GetUserConfigDir()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get user config directory: %w", err)
+	}
+	indexPath := filepath.Join(configDir, keyIndexFile)
+	if err := utils.CreateDirWithPlatformPermissions(configDir); err != nil {
+		return 0, fmt.Errorf("failed to create config directory: %w", err)
+	}
+	unlock, err := utils.LockFile(indexPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to lock API key index: %w", err)
+	}
+	defer unlock()
+
+	positions := make(map[string]int)
+	if data, err := os.ReadFile(indexPath); err == nil {
+		// A corrupt index file only resets the rotation
+		_ = json.Unmarshal(data, &positions)
+	}
+
+	index := positions[entry] % count
+	if index < 0 {
+		index = 0
+	}
+	positions[entry] = (index + 1) % count
+
+	data, err := json.MarshalIndent(positions, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	if err := utils.WriteFileAtomic(indexPath, append(data, '\n')); err != nil {
+		return 0, fmt.Errorf("failed to save API key index: %w", err)
+	}
+	return index, nil
+}
+
+// failoverProvider retries a call with the next API key when the API rejects the key or rate limits it
+type failoverProvider struct {
+	keys      []string
+	providers []llm.Provider
+}
+
+// newFailoverProvider creates a provider per key, calls are tried with the keys in order
+func newFailoverProvider(keys []string, newProvider func(apiKey string) (llm.Provider, error)) (*failoverProvider, error) {
+	providers := make([]llm.Provider, len(keys))
+	for i, key := range keys {
+		provider, err := newProvider(key)
+		if err != nil {
+			return nil, err
+		}
+		providers[i] = provider
+	}
+	return &failoverProvider{keys: keys, providers: providers}, nil
+}
+
+// Call calls the API with the first key, moving to the next key on 401 and 429 responses
+// The template has its variables replaced with the first key, later keys are swapped in literally
+func (p *failoverProvider) Call(template *templates.Template) (*llm.Result, error) {
+	var lastErr error
+	for i, key := range p.keys {
+		attempt := template
+		if i > 0 {
+			fmt.Fprintf(os.Stderr, "Warning: API key %s was rejected, retrying with %s\n", utils.MaskSecret(p.keys[i-1]), utils.MaskSecret(key))
+			attempt = template.Clone().ReplaceText(p.keys[0], key)
+		}
+
+		result, err := p.providers[i].Call(attempt)
+		if err == nil || !isKeyRejected(err) {
+			return result, err
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// isKeyRejected reports whether the API rejected the key or rate limited it
+func isKeyRejected(err error) bool {
+	var responseErr *llm.ResponseError
+	if !errors.As(err, &responseErr) {
+		return false
+	}
+	return responseErr.StatusCode == http.StatusUnauthorized || responseErr.StatusCode == http.StatusTooManyRequests
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/nodewee/llm-caller/pkg/llm"
+	"github.com/nodewee/llm-caller/pkg/templates"
+	"github.com/nodewee/llm-caller/pkg/utils"
+)
+
+// auditEntry is one line of the audit log
+// Request and response bodies and the API key are never logged
+type auditEntry struct {
+	Timestamp  string     `json:"timestamp"`
+	Template   string     `json:"template"`
+	Provider   string     `json:"provider"`
+	Method     string     `json:"method"`
+	URL        string     `json:"url"`
+	Status     int        `json:"status,omitempty"`
+	DurationMS int64      `json:"duration_ms"`
+	Success    bool       `json:"success"`
+	Error      string     `json:"error,omitempty"`
+	Usage      *llm.Usage `json:"usage,omitempty"`
+}
+
+// auditProvider wraps a provider and appends an audit log entry for every call
+type auditProvider struct {
+	provider     llm.Provider
+	logFile      string
+	templateName string
+	apiKey       string
+	mu           sync.Mutex
+}
+
+// newAuditProvider wraps provider so that every call is logged to logFile
+func newAuditProvider(provider llm.Provider, logFile, templateName, apiKey string) (*auditProvider, error) {
+	if err := utils.CreateDirWithPlatformPermissions(filepath.Dir(logFile)); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+	return &auditProvider{
+		provider:     provider,
+		logFile:      logFile,
+		templateName: templateName,
+		apiKey:       apiKey,
+	}, nil
+}
+
+// Call calls the wrapped provider and logs the outcome
+func (p *auditProvider) Call(template *templates.Template) (*llm.Result, error) {
+	start := time.Now()
+	result, callErr := p.provider.Call(template)
+
+	entry := auditEntry{
+		Timestamp:  start.UTC().Format(time.RFC3339),
+		Template:   p.templateName,
+		Provider:   template.Provider,
+		Method:     template.Request.Method,
+		URL:        p.redact(template.Request.URL),
+		DurationMS: time.Since(start).Milliseconds(),
+		Success:    callErr == nil,
+	}
+	if result != nil {
+		entry.Status = result.StatusCode
+		entry.Usage = result.Usage
+	}
+	if callErr != nil {
+		// Errors after a response may quote the response body, so only the status is logged for them
+		var responseErr *llm.ResponseError
+		if errors.As(callErr, &responseErr) {
+			entry.Status = responseErr.StatusCode
+		} else {
+			entry.Error = p.redact(callErr.Error())
+		}
+	}
+
+	if err := p.write(entry); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to write audit log: %v\n", err)
+	}
+	return result, callErr
+}
+
+// write appends entry as a JSON line to the log file
+func (p *auditProvider) write(entry auditEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	file, err := os.OpenFile(p.logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, utils.GetFilePermissions())
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// redact removes the API key from a logged value
+func (p *auditProvider) redact(value string) string {
+	return utils.Redact(value, p.apiKey)
+}
@@ -0,0 +1,311 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/nodewee/llm-caller/pkg/llm"
+	"github.com/nodewee/llm-caller/pkg/templates"
+)
+
+// Batch and repeat output formats
+const (
+	formatText = "text"
+	formatJSON = "json"
+)
+
+// batchInput is one non-empty line of the batch file
+type batchInput struct {
+	Line  int
+	Value string
+}
+
+// batchResult holds the outcome of one template invocation in batch or repeat mode
+type batchResult struct {
+	Line   int             `json:"line,omitempty"`
+	Run    int             `json:"run,omitempty"`
+	Input  string          `json:"input,omitempty"`
+	Output string          `json:"output,omitempty"`
+	Fields json.RawMessage `json:"fields,omitempty"`
+	Error  string          `json:"error,omitempty"`
+
+	done bool
+}
+
+// callJob is one template invocation with its own variables
+type callJob struct {
+	label  string
+	vars   map[string]string
+	result batchResult
+}
+
+// validateMultiCallFlags checks the batch and repeat related flags before any input is read
+func validateMultiCallFlags(repeat bool) error {
+	if batchFlag != "" && repeat {
+		return fmt.Errorf("--batch and --repeat cannot be combined")
+	}
+	if saveRawFlag != "" {
+		return fmt.Errorf("--save-raw cannot be used with --batch or --repeat")
+	}
+	if reprocessFlag != "" {
+		return fmt.Errorf("--reprocess cannot be used with --batch or --repeat")
+	}
+	if len(fallbackFlags) > 0 {
+		return fmt.Errorf("--fallback cannot be used with --batch or --repeat")
+	}
+	if pingFlag {
+		return fmt.Errorf("--ping cannot be used with --batch or --repeat")
+	}
+	if dryRunFlag {
+		return fmt.Errorf("--dry-run cannot be used with --batch or --repeat")
+	}
+	if showCurlFlag || curlSecretsFlag {
+		return fmt.Errorf("--show-curl cannot be used with --batch or --repeat")
+	}
+	if outputDirFlag != "" {
+		return fmt.Errorf("--output-dir cannot be used with --batch or --repeat")
+	}
+	if outputTmplFlag != "" {
+		return fmt.Errorf("--output-template cannot be used with --batch or --repeat")
+	}
+	if streamFlag {
+		return fmt.Errorf("--stream cannot be used with --batch or --repeat")
+	}
+	if batchFlag != "" && batchVarFlag == "" {
+		return fmt.Errorf("--batch-var cannot be empty")
+	}
+	if repeat && repeatFlag < 1 {
+		return fmt.Errorf("--repeat must be at least 1, got %d", repeatFlag)
+	}
+	if concurrencyFlag < 1 {
+		return fmt.Errorf("--concurrency must be at least 1, got %d", concurrencyFlag)
+	}
+	if formatFlag != formatText && formatFlag != formatJSON {
+		return fmt.Errorf("unsupported format '%s', supported formats: %s, %s", formatFlag, formatText, formatJSON)
+	}
+	return nil
+}
+
+// runBatch calls the template once per batch input line and writes the collected outputs
+func runBatch(provider llm.Provider, template *templates.Template, baseVars map[string]string) error {
+	inputs, err := readBatchInputs(batchFlag)
+	if err != nil {
+		return err
+	}
+	if len(inputs) == 0 {
+		return fmt.Errorf("batch file %s contains no input lines", batchFlag)
+	}
+
+	jobs := make([]callJob, len(inputs))
+	for i, input := range inputs {
+		vars := copyVars(baseVars)
+		vars[batchVarFlag] = input.Value
+		jobs[i] = callJob{
+			label:  fmt.Sprintf("Batch line %d", input.Line),
+			vars:   vars,
+			result: batchResult{Line: input.Line, Input: input.Value},
+		}
+	}
+
+	return runJobs(provider, template, jobs)
+}
+
+// runRepeat calls the template repeatFlag times with the same variables
+func runRepeat(provider llm.Provider, template *templates.Template, baseVars map[string]string) error {
+	jobs := make([]callJob, repeatFlag)
+	for i := range jobs {
+		jobs[i] = callJob{
+			label:  fmt.Sprintf("Run %d", i+1),
+			vars:   baseVars,
+			result: batchResult{Run: i + 1},
+		}
+	}
+
+	return runJobs(provider, template, jobs)
+}
+
+// runJobs runs the jobs with bounded concurrency and writes the results in job order
+// Failures are recorded in the results; only --fail-fast stops the remaining jobs
+func runJobs(provider llm.Provider, template *templates.Template, jobs []callJob) error {
+	results := make([]batchResult, len(jobs))
+	var usageMu sync.Mutex
+	var totalUsage llm.Usage
+	usageReported := false
+	semaphore := make(chan struct{}, concurrencyFlag)
+	stop := make(chan struct{})
+	var stopOnce sync.Once
+	var wg sync.WaitGroup
+
+	for i, job := range jobs {
+		// Wait for a free slot, unless a failure stopped the run
+		select {
+		case semaphore <- struct{}{}:
+		case <-stop:
+		}
+		if isStopped(stop) {
+			break
+		}
+
+		wg.Add(1)
+		go func(i int, job callJob) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			result := job.result
+			result.done = true
+			var output *llm.Result
+			jobTemplate, err := template.Clone().ReplaceVariables(job.vars)
+			if err == nil && countTokensFlag {
+				printTokenEstimate(job.label, jobTemplate)
+			}
+			if err == nil {
+				output, err = provider.Call(jobTemplate)
+			}
+			if err == nil && failOnEmptyFlag {
+				err = checkEmptyResult(output)
+			}
+			if err != nil {
+				result.Error = err.Error()
+				fmt.Fprintf(os.Stderr, "%s failed: %v\n", job.label, err)
+				if failFastFlag {
+					stopOnce.Do(func() { close(stop) })
+				}
+			} else {
+				// Named fields are kept as a JSON object instead of a string holding JSON
+				if len(template.Response.Paths) > 0 && json.Valid([]byte(output.Content)) {
+					result.Fields = json.RawMessage(output.Content)
+				} else {
+					result.Output = output.Content
+				}
+				if output.Usage != nil {
+					usageMu.Lock()
+					totalUsage.Add(*output.Usage)
+					usageReported = true
+					usageMu.Unlock()
+				}
+			}
+			results[i] = result
+		}(i, job)
+	}
+	wg.Wait()
+
+	// Collect the processed results in job order
+	var processed []batchResult
+	failed := 0
+	for _, result := range results {
+		if !result.done {
+			continue
+		}
+		processed = append(processed, result)
+		if result.Error != "" {
+			failed++
+		}
+	}
+
+	if showUsageFlag {
+		if usageReported {
+			printUsage(totalUsage, template.Pricing)
+		} else {
+			fmt.Fprintln(os.Stderr, "Usage: not reported by the API")
+		}
+	}
+
+	output, err := formatBatchResults(processed)
+	if err != nil {
+		return err
+	}
+	if err := writeOutput([]byte(output), outputFlags, appendFlag); err != nil {
+		return err
+	}
+
+	if failFastFlag && failed > 0 {
+		return fmt.Errorf("stopped after a failure (%d of %d calls processed)", len(processed), len(jobs))
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d calls failed", failed, len(jobs))
+	}
+	return nil
+}
+
+// copyVars returns a copy of the variables map
+func copyVars(vars map[string]string) map[string]string {
+	result := make(map[string]string, len(vars)+1)
+	for name, value := range vars {
+		result[name] = value
+	}
+	return result
+}
+
+// isStopped reports whether the stop channel has been closed
+func isStopped(stop chan struct{}) bool {
+	select {
+	case <-stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// readBatchInputs reads the non-empty lines of a batch file, '-' meaning stdin
+func readBatchInputs(path string) ([]batchInput, error) {
+	var reader io.Reader
+	if path == "-" {
+		reader = os.Stdin
+	} else {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open batch file %s: %w", path, err)
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	var inputs []batchInput
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		value := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(value) == "" {
+			continue
+		}
+		inputs = append(inputs, batchInput{Line: line, Value: value})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read batch file %s: %w", path, err)
+	}
+
+	return inputs, nil
+}
+
+// formatBatchResults renders batch and repeat results as newline-delimited text or a JSON array
+func formatBatchResults(results []batchResult) (string, error) {
+	if formatFlag == formatJSON {
+		if results == nil {
+			results = []batchResult{}
+		}
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal batch results: %w", err)
+		}
+		return string(data) + "\n", nil
+	}
+
+	var builder strings.Builder
+	for _, result := range results {
+		if result.Error != "" {
+			continue
+		}
+		builder.WriteString(result.Output)
+		if !strings.HasSuffix(result.Output, "\n") {
+			builder.WriteString("\n")
+		}
+	}
+	return builder.String(), nil
+}
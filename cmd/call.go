@@ -1,27 +1,102 @@
 package cmd
 
 import (
+	"bytes"
+	"context"
 	"encoding/base64"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
+	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/nodewee/llm-caller/pkg/config"
 	"github.com/nodewee/llm-caller/pkg/llm"
 	"github.com/nodewee/llm-caller/pkg/templates"
 	"github.com/nodewee/llm-caller/pkg/utils"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 )
 
+// varTypeJSON is the variable type whose value is spliced into the body as a JSON value
+const varTypeJSON = "json"
+
+// varTypeBase64File is the variable type whose value is the base64 encoding of a file's bytes, e.g. for images
+const varTypeBase64File = "base64file"
+
+// varTypeCSV is the variable type whose CSV file becomes a JSON array of row objects, spliced in like json
+const varTypeCSV = "csv"
+
+// jsonFilePrefix selects a file as the source of a json variable, e.g. tools:json:file:tools.json
+const jsonFilePrefix = "file:"
+
 // Call command flags
 var (
 	varFlags           []string
+	varFileFlag        string
 	apiKeyFlag         string
-	outputFlag         string
+	apiKeyCmdFlag      string
+	apiKeyFileFlag     string
+	outputFlags        []string
+	appendFlag         bool
+	outputDirFlag      string
+	outputPatternFlag  string
+	outputTmplFlag     string
+	saveRawFlag        string
+	fallbackFlags      []string
+	pingFlag           bool
+	dryRunFlag         bool
+	countTokensFlag    bool
+	showCurlFlag       bool
+	curlSecretsFlag    bool
+	reprocessFlag      string
 	templateJSONFlag   string
+	templateYAMLFlag   string
+	printTemplatePath  bool
 	templateBase64Flag string
+	proxyFlag          string
+	userAgentFlag      string
+	caCertFlag         string
+	insecureFlag       bool
+	maxResponseFlag    int64
+	maxVarBytesFlag    int64
+	failOnLargeVarFlag bool
+	verboseFlag        bool
+	showUsageFlag      bool
+	streamFlag         bool
+	batchFlag          string
+	batchVarFlag       string
+	formatFlag         string
+	concurrencyFlag    int
+	maxIdleConnsFlag   int
+	failFastFlag       bool
+	repeatFlag         int
+	temperatureFlag    float64
+	maxTokensFlag      int
+	seedFlag           int64
+	modelFlag          string
+	modelPathFlag      string
+	logFlag            string
+	keyStrategyFlag    string
+	postHookFlag       string
+	varBaseDirFlag     string
+	inputFlag          string
+	inputVarFlag       string
+	inputTypeFlag      string
+	failOnEmptyFlag    bool
+	strictFlag         bool
+	saveHistoryFlag    bool
+	noRateLimitFlag    bool
+	templateStdinFlag  bool
+	postHookTimeout    time.Duration
 )
 
 // Call command - main functionality
@@ -35,8 +110,12 @@ The result is either printed to stdout or saved to a file.
 
 Template Sources (mutually exclusive):
 1. Template file: llm-caller call <template-name>
+   Names without an extension match <name>.json, then <name>.yaml and <name>.yml
 2. JSON string: llm-caller call --template-json '{"provider":"..."}'
-3. Base64 encoded: llm-caller call --template-base64 "eyJ..."
+3. YAML string: llm-caller call --template-yaml "$(cat template.yaml)"
+4. Base64 encoded: llm-caller call --template-base64 "eyJ..."
+5. Stdin: generate-template | llm-caller call --template-stdin (or 'llm-caller call -')
+   JSON or YAML content. Variables cannot read stdin ('-') at the same time.
 
 Variable Types & Data Handling:
 - name:value (default type is 'text')
@@ -44,14 +123,103 @@ Variable Types & Data Handling:
   - text: Use value as-is. If value is '-', read raw content from stdin.
   - file: Reads content from a file path. The file content is used as a raw string without any special encoding.
     - If path is '-', reads raw content from stdin.
+    - Relative paths are resolved against --var-base-dir if set, otherwise the current directory.
+  - json: A JSON value, or 'file:<path>' / '-' to read it. A body string that is exactly
+    "{{name}}" is replaced by the parsed value (e.g. a tools array) instead of quoted text.
+  - csv: A CSV file (or '-') whose header row names the fields; becomes a JSON array of
+    row objects, spliced in like json (e.g. few-shot examples).
+
+Input (--input):
+- --input <file> (or '-' for stdin) is a shorthand for --var prompt:file:<file>
+- --input-var selects another variable name; setting the same variable with --var is an error
+
+Variable File (--var-file):
+- JSON (.json) or YAML (.yaml, .yml) map of name to value, merged before --var flags
+- Values are text by default; use {"type": "file", "value": "./x.png"} to select a type
+- {"type": "json", "value": [...]} uses the structured value as a json variable
+
+Default Variables:
+- Template "defaults" map and config 'default.<name>' keys provide default values
+- Precedence: --var / --var-file > template defaults > config defaults
 
 API keys are checked in this order:
 1. --api-key command line flag
-2. Keys file (configured with 'config secret_file')
-3. Environment variables (provider-specific keys checked first)
+2. --api-key-file command line flag (the file's trimmed first line is the key)
+3. --api-key-cmd command line flag (the command's trimmed stdout is the key)
+4. Keys file (configured with 'config secret_file'); values of the form
+   "cmd:<command>" run the command and use its output. A value can be an
+   array of keys, used according to --key-strategy
+5. Environment variables (provider-specific keys checked first)
 
 API keys are optional for local LLMs like Ollama that don't require authentication.
 
+Batch mode (--batch) calls the template once per non-empty line of a file, passing
+the line in the variable named by --batch-var (default 'prompt'). Outputs are written
+newline-delimited (--format text) or as a JSON array (--format json). Up to
+--concurrency requests run in parallel. Failed lines are reported on stderr and do
+not stop the batch unless --fail-fast is set.
+
+Response bodies larger than --max-response-bytes (default 32 MiB) are rejected
+with an error instead of being read into memory. Use 0 for unlimited.
+
+File variables (file type, json file:<path> and --input) larger than --max-var-bytes
+(default 1 MiB) print a warning, as they may exceed the model's context or be costly.
+With --fail-on-large-var the call fails instead, without reading past the limit.
+Use 0 to disable the check.
+
+--post-hook "<command>" runs a shell command for every result, passing the result
+on stdin and using its stdout as the output (e.g. a formatter, or a script saving it
+to a database). Binary responses pass their raw bytes through the command.
+A non-zero exit status or exceeding --post-hook-timeout (default 60s) fails the call.
+
+For self-hosted endpoints with a private CA, use --cacert <file> to trust the CA.
+--insecure skips certificate verification entirely and prints a warning.
+
+Repeat mode (--repeat N) calls the template N times with the same variables, e.g.
+to sample multiple completions. Results are collected like in batch mode, with
+failures recorded per run instead of aborting.
+
+Templates with a response "paths" map (name to path) extract several values at once,
+e.g. the content and the finish_reason, and output them as a JSON object. They require
+--format json; in batch/repeat mode the object is stored in each result's "fields".
+
+--temperature, --max-tokens and --seed set the matching top-level body field, adding it when
+the template body lacks it. --max-tokens uses the body's max_tokens, max_completion_tokens,
+max_output_tokens or num_predict field, or adds max_tokens.
+
+Use --fail-on-empty to fail when the extracted content is empty or only whitespace,
+e.g. when the model returned nothing or response.path points at the wrong field.
+The raw response is printed with the error. This also applies to each batch/repeat call.
+
+Use --strict to fail before any request is sent when a {{placeholder}} in the URL, headers
+or body has no value and no inline default, including {{api_key}}. Without it such
+placeholders are sent literally. 'config strict true' makes strict mode the default.
+
+Use --dry-run to print the resolved request (method, URL, headers and body) instead of
+sending it; API keys are redacted. --count-tokens prints a rough token estimate of the
+text in the resolved body to stderr (and its cost if the template has "pricing"), e.g.
+to budget a long document before sending it, or together with --dry-run to only count.
+--show-curl prints an equivalent curl command to stderr, with the API key redacted
+unless --show-curl-secrets is used instead.
+
+Use --show-usage to print the token usage reported by the API to stderr. If the
+template has a "pricing" block, the estimated cost is printed as well.
+
+Use --stream to print the content of an OpenAI-compatible API as it is generated.
+It sets "stream" and "stream_options.include_usage" in the body; the usage of the
+final chunk before [DONE] is printed by --show-usage. It works for single calls
+to stdout only.
+
+Requests wait for the provider's rate limit if one is configured with
+'config ratelimit.<provider> 3/s' (units s, m, h). The limit is shared by all
+llm-caller processes and batch workers; --no-ratelimit ignores it.
+
+Proxy settings are taken from HTTP_PROXY, HTTPS_PROXY and NO_PROXY by default.
+Use --proxy to set an explicit proxy URL (http, https or socks5).
+
+Use --verbose to log the request URL, headers, body size, response status, timing
+and raw response body to stderr. The API key is redacted and stdout stays clean.
+
 Examples:
   # Using template file
   llm-caller call deepseek-chat --var "prompt:Hello world"
@@ -59,9 +227,15 @@ Examples:
   # Handle large data via file
   llm-caller call open-chat --var "image:file:./image.png"
 
+  # Load many variables from a file
+  llm-caller call translate --var-file vars.yaml --var "text:Hello"
+
   # Pipe content from stdin
   cat README.md | llm-caller call my-template --var "prompt:text:-"
   cat image.png | llm-caller call my-template --var "image:file:-"
+  git diff | llm-caller call review --input -
+  llm-caller call summarize --input notes.md --input-var text
+  cat photo.png | llm-caller call describe-image --input - --input-var image --input-type base64file
   
   # Using JSON string
   llm-caller call --template-json '{"provider":"deepseek","request":{"url":"https://api.deepseek.com/chat/completions","headers":{"Authorization":"Bearer {{api_key}}"},"body":{"model":"deepseek-chat","messages":[{"role":"user","content":"{{prompt}}"}]}}}' --var "prompt:Hello world"
@@ -70,58 +244,232 @@ Examples:
   llm-caller call --template-base64 "eyJwcm92aWRlciI6ImRlZXBzZWVrIiwicmVxdWVzdCI6eyJ1cmwiOiJodHRwczovL2FwaS5kZWVwc2Vlay5jb20vY2hhdC9jb21wbGV0aW9ucyIsImhlYWRlcnMiOnsiQXV0aG9yaXphdGlvbiI6IkJlYXJlciB7e2FwaV9rZXl9fSJ9LCJib2R5Ijp7Im1vZGVsIjoiZGVlcHNlZWstY2hhdCIsIm1lc3NhZ2VzIjpbeyJyb2xlIjoidXNlciIsImNvbnRlbnQiOiJ7e3Byb21wdH19In1dfX19" --var "prompt:Hello world"
   
   # Local LLM (API key optional)
-  llm-caller call ollama-local --var "prompt:Tell me a joke"`,
+  llm-caller call ollama-local --var "prompt:Tell me a joke"
+
+  # Write to a file and stdout, appending to the file
+  llm-caller call deepseek-chat --var "prompt:Hello" -o chat.log -o - --append
+
+  # Run one prompt per line of a file, 4 at a time, collecting a JSON array
+  llm-caller call deepseek-chat --batch prompts.txt --batch-var prompt --concurrency 4 --format json -o results.json
+
+  # Sample 5 completions in parallel at a higher temperature
+  llm-caller call deepseek-chat --var "prompt:Write a haiku" --repeat 5 --concurrency 5 --temperature 1.2 --format json
+
+  # Through an explicit proxy
+  llm-caller call deepseek-chat --var "prompt:Hello" --proxy socks5://127.0.0.1:1080`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: runCall,
 }
 
 func init() {
 	// Call command flags
-	callCmd.Flags().StringArrayVar(&varFlags, "var", []string{}, "Variable in 'name[:type]:value' format (e.g., 'prompt:file:my.txt'). Type can be 'text' or 'file'. Use '-' to read from stdin.")
+	callCmd.Flags().StringArrayVar(&varFlags, "var", []string{}, "Variable in 'name[:type]:value' format (e.g., 'prompt:file:my.txt'). Type can be 'text' (default), 'file', 'base64file', 'json' or 'csv'. Use '-' to read from stdin.")
+	callCmd.Flags().StringVar(&varFileFlag, "var-file", "", "JSON or YAML file with variables; --var flags take precedence")
+	callCmd.Flags().StringVar(&inputFlag, "input", "", "File whose content becomes the --input-var variable, '-' for stdin (shorthand for --var prompt:file:<path>)")
+	callCmd.Flags().StringVar(&inputVarFlag, "input-var", "prompt", "Variable that receives the --input content")
+	callCmd.Flags().StringVar(&inputTypeFlag, "input-type", "text", "How the --input content is stored: 'text' (or 'file') as is, 'base64file' base64 encoded, e.g. for piped images")
+	callCmd.Flags().StringVar(&varBaseDirFlag, "var-base-dir", "", "Directory relative file variable paths are resolved against (default: current directory)")
 	callCmd.Flags().StringVar(&apiKeyFlag, "api-key", "", "API key (optional, overrides config and environment)")
-	callCmd.Flags().StringVarP(&outputFlag, "output", "o", "", "Output file path (default: stdout)")
+	callCmd.Flags().StringVar(&apiKeyFileFlag, "api-key-file", "", "File whose first line is the API key, e.g. a mounted Kubernetes or Docker secret")
+	callCmd.Flags().StringVar(&apiKeyCmdFlag, "api-key-cmd", "", "Command whose output is the API key, e.g. 'op read op://vault/item/key'")
+	callCmd.Flags().StringArrayVarP(&outputFlags, "output", "o", []string{}, "Output file path, '-' for stdout; repeat to write to several targets (default: stdout)")
+	callCmd.Flags().BoolVar(&appendFlag, "append", false, "Append to output files instead of overwriting them")
 	callCmd.Flags().StringVar(&templateJSONFlag, "template-json", "", "Template as JSON string (mutually exclusive with template file and --template-base64)")
+	callCmd.Flags().BoolVar(&printTemplatePath, "print-template-path", false, "Print the absolute path of the loaded template file to stderr (also shown with --verbose)")
+	callCmd.Flags().StringVar(&templateYAMLFlag, "template-yaml", "", "Template as YAML string (mutually exclusive with the other template sources)")
 	callCmd.Flags().StringVar(&templateBase64Flag, "template-base64", "", "Template as Base64 encoded JSON (mutually exclusive with template file and --template-json)")
+	callCmd.Flags().BoolVar(&templateStdinFlag, "template-stdin", false, "Read the template (JSON or YAML) from stdin (same as template '-')")
+	callCmd.Flags().StringVar(&batchFlag, "batch", "", "File with one input per line ('-' for stdin); the template is called once per non-empty line")
+	callCmd.Flags().StringVar(&batchVarFlag, "batch-var", "prompt", "Variable that receives each batch input line")
+	callCmd.Flags().IntVar(&repeatFlag, "repeat", 1, "Call the template N times with the same variables (e.g. to sample completions)")
+	callCmd.Flags().StringVar(&formatFlag, "format", formatText, "Output format: 'text' or 'json'; batch/repeat results become newline-delimited text or a JSON array, templates with response.paths require 'json'; with 'json' errors are JSON objects on stderr")
+	callCmd.Flags().IntVar(&concurrencyFlag, "concurrency", 1, "Maximum number of parallel requests in batch/repeat mode")
+	callCmd.Flags().IntVar(&maxIdleConnsFlag, "max-idle-conns", 0, "Idle connections kept per host for reuse across requests (default: --concurrency, at least Go's default of 2)")
+	callCmd.Flags().BoolVar(&failFastFlag, "fail-fast", false, "Stop the batch/repeat run on the first failure")
+	callCmd.Flags().Float64Var(&temperatureFlag, "temperature", 0, "Set the 'temperature' field of the request body, adding it if missing")
+	callCmd.Flags().IntVar(&maxTokensFlag, "max-tokens", 0, "Set the token limit field of the request body (max_tokens unless the body uses another name), adding it if missing")
+	callCmd.Flags().Int64Var(&seedFlag, "seed", 0, "Set the 'seed' field of the request body, adding it if missing")
+	callCmd.Flags().StringVar(&modelFlag, "model", "", "Model to use: sets the body's 'model' field and the {{model}} variable, or the field named by --model-path")
+	callCmd.Flags().StringVar(&modelPathFlag, "model-path", "", "Dot path of the request body field --model sets when it is not a top-level 'model', e.g. 'options.model'")
+	callCmd.Flags().BoolVarP(&verboseFlag, "verbose", "v", false, "Log request and response details to stderr (API key is redacted)")
+	callCmd.Flags().BoolVar(&failOnEmptyFlag, "fail-on-empty", false, "Fail when the extracted content is empty or only whitespace, printing the raw response")
+	callCmd.Flags().BoolVar(&strictFlag, "strict", false, "Fail when a {{placeholder}} has no value instead of sending it literally (default from 'config strict')")
+	callCmd.Flags().BoolVar(&showUsageFlag, "show-usage", false, "Print token usage (and estimated cost if the template has pricing) to stderr")
+	callCmd.Flags().BoolVar(&streamFlag, "stream", false, "Request an event stream from an OpenAI-compatible API (sets 'stream' and 'stream_options.include_usage' in the body) and print the content to stdout as it arrives")
+	callCmd.Flags().StringVar(&proxyFlag, "proxy", "", "Proxy URL (http, https or socks5), overrides HTTP_PROXY/HTTPS_PROXY")
+	callCmd.Flags().StringVar(&caCertFlag, "cacert", "", "PEM file with additional CA certificates to trust (e.g. for a private gateway)")
+	callCmd.Flags().Int64Var(&maxResponseFlag, "max-response-bytes", llm.DefaultMaxResponseBytes, "Maximum response body size in bytes, 0 for unlimited")
+	callCmd.Flags().Int64Var(&maxVarBytesFlag, "max-var-bytes", defaultMaxVarBytes, "Warn when a file variable is larger than this many bytes, 0 to disable")
+	callCmd.Flags().BoolVar(&failOnLargeVarFlag, "fail-on-large-var", false, "Fail instead of warning when a file variable exceeds --max-var-bytes")
+	callCmd.Flags().BoolVar(&noRateLimitFlag, "no-ratelimit", false, "Ignore the provider's configured rate limit (ratelimit.<provider>)")
+	callCmd.Flags().StringVar(&userAgentFlag, "user-agent", "", "User-Agent header to send, overriding the template's (default: the template's, or the llm-caller URL)")
+	callCmd.Flags().BoolVar(&insecureFlag, "insecure", false, "Skip TLS certificate verification (unsafe, for testing only)")
+	callCmd.Flags().StringVar(&keyStrategyFlag, "key-strategy", keyStrategyFailover, "How to use a secret file entry with several keys: 'failover' (next key on 401/429) or 'round-robin' (next key per invocation)")
+	callCmd.Flags().StringVar(&postHookFlag, "post-hook", "", "Shell command that receives each result on stdin; its stdout becomes the output")
+	callCmd.Flags().DurationVar(&postHookTimeout, "post-hook-timeout", defaultPostHookTimeout, "Maximum run time of the post hook command")
+	callCmd.Flags().StringVar(&outputDirFlag, "output-dir", "", "Write each file extracted by a response.mode files template (e.g. generated images) to this directory")
+	callCmd.Flags().StringVar(&outputPatternFlag, "output-pattern", defaultOutputPattern, "File name pattern for --output-dir; {i} is the file's position from 1, {ext} an extension detected from the content")
+	callCmd.Flags().StringVar(&outputTmplFlag, "output-template", "", "Format the result is written in, e.g. '## Result\\n{{content}}\\n'; {{content}}, {{template}} and {{timestamp}} are replaced, \\n and \\t are unescaped")
+	callCmd.Flags().StringVar(&saveRawFlag, "save-raw", "", "Also write the unmodified response body to this file, even if extraction fails")
+	callCmd.Flags().StringArrayVar(&fallbackFlags, "fallback", []string{}, "Template to try when the call fails with a network error or error status; repeatable, tried in order")
+	callCmd.Flags().BoolVar(&pingFlag, "ping", false, "Check that the API is reachable and the key works (GET health_url if the template has one, else a minimal request) instead of calling it")
+	callCmd.Flags().BoolVar(&dryRunFlag, "dry-run", false, "Print the resolved request (method, URL, headers and body) instead of sending it, with secrets redacted")
+	callCmd.Flags().BoolVar(&showCurlFlag, "show-curl", false, "Print an equivalent curl command of the resolved request to stderr, with secrets redacted")
+	callCmd.Flags().BoolVar(&curlSecretsFlag, "show-curl-secrets", false, "Like --show-curl, but include the API key and signing secret in the curl command")
+	callCmd.Flags().BoolVar(&countTokensFlag, "count-tokens", false, "Print a heuristic token estimate of the resolved request body to stderr before sending it")
+	callCmd.Flags().StringVar(&reprocessFlag, "reprocess", "", "Extract the result from a saved JSON response body (e.g. from --save-raw) instead of calling the API")
+	callCmd.Flags().BoolVar(&saveHistoryFlag, "save-history", false, "Record the call in the history listed by 'llm-caller history', with inline --var values redacted (default from 'config save_history')")
+	callCmd.Flags().StringVar(&logFlag, "log", "", "Append a JSON audit line per call to this file (overrides the log_file config)")
 }
 
 // runCall handles the call command
+// With --format json, errors are written to stderr as a JSON object naming the stage that failed
+// Ctrl-C cancels the request in flight and nothing is written to the outputs
 func runCall(cmd *cobra.Command, args []string) error {
+	ctx, cancel := interruptContext(cmd.Context())
+	defer cancel()
+	cmd.SetContext(ctx)
+
+	err := utils.RedactError(callTemplate(cmd, args), resolvedSecrets...)
+	if isSaveHistory(cmd) {
+		templateName := ""
+		if len(args) > 0 {
+			templateName = args[0]
+		}
+		if historyErr := saveHistory(templateName, err == nil); historyErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to save history: %v\n", historyErr)
+		}
+	}
+	if err != nil && ctx.Err() != nil {
+		cmd.SilenceErrors = true
+		return errCancelled
+	}
+	if err != nil && formatFlag == formatJSON {
+		cmd.SilenceErrors = true
+		writeJSONError(os.Stderr, err)
+		return errReported
+	}
+	return err
+}
+
+// callTemplate loads the template, calls the API and writes the result
+func callTemplate(cmd *cobra.Command, args []string) (err error) {
+	// Tag errors with the stage reached for structured error output
+	stage := stageArguments
+	defer func() {
+		err = withStage(stage, err)
+	}()
+
 	// Validate template source arguments (mutually exclusive)
 	templateSources := 0
 	var templateFlag string
 
-	if len(args) > 0 && args[0] != "" {
+	if len(args) > 0 && args[0] == "-" {
+		templateStdinFlag = true
+	} else if len(args) > 0 && args[0] != "" {
 		templateSources++
 		templateFlag = args[0]
 	}
+	if templateStdinFlag {
+		templateSources++
+	}
 	if cmd.Flags().Changed("template-json") {
 		templateSources++
 	}
+	if cmd.Flags().Changed("template-yaml") {
+		templateSources++
+	}
 	if cmd.Flags().Changed("template-base64") {
 		templateSources++
 	}
 
 	if templateSources == 0 {
-		return fmt.Errorf("must specify a template source: template file, --template-json, or --template-base64")
+		return fmt.Errorf("must specify a template source: template file, --template-json, --template-yaml, --template-base64, or --template-stdin")
 	}
 	if templateSources > 1 {
-		return fmt.Errorf("template sources are mutually exclusive: specify only one of template file, --template-json, or --template-base64")
+		return fmt.Errorf("template sources are mutually exclusive: specify only one of template file, --template-json, --template-yaml, --template-base64, or --template-stdin")
+	}
+	if templateStdinFlag {
+		if consumers := stdinConsumers(); len(consumers) > 0 {
+			return fmt.Errorf("the template is read from stdin, so stdin cannot also be used by %s", strings.Join(consumers, ", "))
+		}
 	}
 
-	// Parse var flags with improved format support
-	replaceVars, err := parseVarFlags(varFlags)
+	if dryRunFlag && pingFlag {
+		return fmt.Errorf("--dry-run cannot be combined with --ping")
+	}
+	if outputDirFlag != "" {
+		if len(outputFlags) > 0 {
+			return fmt.Errorf("--output-dir cannot be combined with --output")
+		}
+		if !strings.Contains(outputPatternFlag, "{i}") {
+			return fmt.Errorf("--output-pattern must contain {i} so that each file gets its own name")
+		}
+	} else if cmd.Flags().Changed("output-pattern") {
+		return fmt.Errorf("--output-pattern requires --output-dir")
+	}
+	if outputTmplFlag != "" && outputDirFlag != "" {
+		return fmt.Errorf("--output-template cannot be combined with --output-dir")
+	}
+	if streamFlag {
+		if err := validateStreamFlags(); err != nil {
+			return err
+		}
+	}
+
+	repeatMode := cmd.Flags().Changed("repeat")
+	if batchFlag != "" || repeatMode {
+		if err := validateMultiCallFlags(repeatMode); err != nil {
+			return err
+		}
+	}
+
+	// Load variables from the var file and --var flags
+	stage = stageVariables
+	if maxVarBytesFlag < 0 {
+		return fmt.Errorf("--max-var-bytes cannot be negative")
+	}
+	replaceVars, jsonVars, err := loadCLIVariables(varFileFlag, varFlags, varBaseDirFlag)
 	if err != nil {
-		return fmt.Errorf("failed to parse var flags: %w", err)
+		return err
+	}
+	if inputFlag != "" {
+		if err := applyInputFlag(replaceVars, jsonVars); err != nil {
+			return err
+		}
+	} else if cmd.Flags().Changed("input-type") {
+		return fmt.Errorf("--input-type requires --input")
 	}
 
 	// Load the template based on the source type
+	stage = stageTemplateLoad
 	var template *templates.Template
 	if templateFlag != "" {
-		// Load from file (existing logic)
-		template, err = templates.LoadTemplate(cfg, templateFlag)
+		// Load from file, reporting which file was used when several directories are searched
+		var templatePath string
+		template, templatePath, err = templates.LoadTemplateWithPath(cfg, templateFlag)
 		if err != nil {
 			return fmt.Errorf("failed to load template: %w", err)
 		}
+		if printTemplatePath {
+			fmt.Fprintf(os.Stderr, "Template: %s\n", templatePath)
+		} else if verboseFlag {
+			fmt.Fprintf(os.Stderr, "[verbose] Template: %s\n", templatePath)
+		}
+	} else if templateStdinFlag {
+		// Load from stdin, e.g. a generated template piped in
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read template from stdin: %w", err)
+		}
+		if templates.IsJSONContent(string(data)) {
+			template, err = templates.LoadTemplateFromJSON(string(data))
+		} else {
+			template, err = templates.LoadTemplateFromYAML(string(data))
+		}
+		if err != nil {
+			return fmt.Errorf("failed to parse template from stdin: %w", err)
+		}
 	} else if cmd.Flags().Changed("template-json") {
 		// Load from JSON string
 		if templateJSONFlag == "" {
@@ -131,6 +479,15 @@ func runCall(cmd *cobra.Command, args []string) error {
 		if err != nil {
 			return fmt.Errorf("failed to parse template JSON: %w", err)
 		}
+	} else if cmd.Flags().Changed("template-yaml") {
+		// Load from YAML string
+		if templateYAMLFlag == "" {
+			return fmt.Errorf("--template-yaml cannot be empty")
+		}
+		template, err = templates.LoadTemplateFromYAML(templateYAMLFlag)
+		if err != nil {
+			return fmt.Errorf("failed to parse template YAML: %w", err)
+		}
 	} else if cmd.Flags().Changed("template-base64") {
 		// Load from Base64 encoded JSON
 		if templateBase64Flag == "" {
@@ -146,64 +503,710 @@ func runCall(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	// Get API key based on priority
-	apiKey, err := getAPIKey(apiKeyFlag, cfg, template)
+	stage = stageSetup
+	warnTemplate(templateDisplayName(templateFlag), template)
+
+	// Run the template's extraction on a saved response, without calling the API
+	if reprocessFlag != "" {
+		stage = stageExtraction
+		if len(template.Response.Paths) > 0 && formatFlag != formatJSON {
+			return fmt.Errorf("the template extracts several fields with response.paths, use --format json")
+		}
+		if err := checkOutputDir(template); err != nil {
+			return err
+		}
+		result, err := reprocessResponse(reprocessFlag, template)
+		if err != nil {
+			return err
+		}
+		return outputResult(result, templateDisplayName(templateFlag), template)
+	}
+
+	// Get API keys based on priority, secret file entries may hold several keys
+	if err := validateKeyStrategy(keyStrategyFlag); err != nil {
+		return err
+	}
+	if maxResponseFlag < 0 {
+		return fmt.Errorf("--max-response-bytes cannot be negative")
+	}
+	if maxIdleConnsFlag < 0 {
+		return fmt.Errorf("--max-idle-conns cannot be negative")
+	}
+	if postHookFlag != "" && postHookTimeout <= 0 {
+		return fmt.Errorf("--post-hook-timeout must be positive")
+	}
+	if insecureFlag {
+		fmt.Fprintln(os.Stderr, "Warning: TLS certificate verification is disabled (--insecure), do not use this in production")
+	}
+
+	call, err := prepareCall(cmd, templateDisplayName(templateFlag), template, replaceVars, jsonVars)
 	if err != nil {
-		return fmt.Errorf("failed to get API key: %w", err)
+		return err
 	}
 
-	// Add api_key to replacement variables if not empty
-	if replaceVars == nil {
-		replaceVars = make(map[string]string)
+	// Print the resolved request without sending it
+	if dryRunFlag {
+		return dryRunCall(call)
+	}
+
+	// Check that the API is reachable and accepts the key, without a full generation
+	stage = stageAPICall
+	if pingFlag {
+		return pingTemplate(call)
+	}
+
+	// Batch mode calls the template once per input line, repeat mode N times
+	if batchFlag != "" {
+		return runBatch(call.provider, call.template, call.vars)
+	}
+	if repeatMode {
+		return runRepeat(call.provider, call.template, call.vars)
+	}
+
+	// Call the provider, trying the --fallback templates in order while calls fail
+	result, err := call.run()
+	for _, fallback := range fallbackFlags {
+		if err == nil || !isFallbackError(err) {
+			break
+		}
+		fmt.Fprintf(os.Stderr, "Warning: template %s failed (%v), trying %s\n", call.name, err, fallback)
+
+		stage = stageTemplateLoad
+		fallbackTemplate, loadErr := templates.LoadTemplate(cfg, fallback)
+		if loadErr != nil {
+			return fmt.Errorf("failed to load fallback template %s: %w", fallback, loadErr)
+		}
+		warnTemplate(fallback, fallbackTemplate)
+		stage = stageSetup
+		if call, err = prepareCall(cmd, fallback, fallbackTemplate, replaceVars, jsonVars); err != nil {
+			return err
+		}
+		stage = stageAPICall
+		result, err = call.run()
+	}
+	if saveRawFlag != "" {
+		if saveErr := saveRawResponse(saveRawFlag, result, err); saveErr != nil {
+			return saveErr
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("LLM call failed: %w", err)
+	}
+	if len(fallbackFlags) > 0 {
+		infof(os.Stderr, "Result from template %s\n", call.name)
+	}
+	return outputResult(result, call.name, call.template)
+}
+
+// templateDisplayName returns the name a template is reported by, "(inline)" for templates not loaded from a file
+func templateDisplayName(templateFlag string) string {
+	if templateFlag == "" {
+		return "(inline)"
+	}
+	return templateFlag
+}
+
+// warnTemplate prints the template's deprecation notice and minimum version warning to stderr
+func warnTemplate(name string, template *templates.Template) {
+	for _, warning := range template.Warnings(buildInfo.Version) {
+		fmt.Fprintf(os.Stderr, "Warning: template %s %s\n", name, warning)
+	}
+}
+
+// resolvedSecrets holds the API keys and signing secrets resolved by prepareCall, they are removed from reported errors
+var resolvedSecrets []string
+
+// preparedCall is a template with its variables and the provider chain that calls it
+type preparedCall struct {
+	name       string
+	template   *templates.Template
+	vars       map[string]string
+	provider   llm.Provider
+	apiKey     string
+	clientOpts llm.ClientOptions
+}
+
+// run replaces the variables and calls the provider
+func (c *preparedCall) run() (*llm.Result, error) {
+	if _, err := c.template.ReplaceVariables(c.vars); err != nil {
+		return nil, withStage(stageVariables, err)
+	}
+	if countTokensFlag {
+		printTokenEstimate("", c.template)
+	}
+	if showCurlFlag || curlSecretsFlag {
+		printCurlCommand(c)
 	}
+	return c.provider.Call(c.template)
+}
+
+// isStrict reports whether unresolved placeholders are rejected, --strict takes precedence over 'config strict'
+func isStrict(cmd *cobra.Command) bool {
+	if cmd.Flags().Changed("strict") {
+		return strictFlag
+	}
+	return cfg.GetBool(config.KeyStrict)
+}
+
+// checkUnresolvedPlaceholders fails if replacing the variables would leave placeholders in the request
+// In batch mode the --batch-var variable is set for each line, so it counts as resolved
+func checkUnresolvedPlaceholders(template *templates.Template, vars map[string]string) error {
+	if batchFlag != "" {
+		vars = copyVars(vars)
+		vars[batchVarFlag] = ""
+	}
+	unresolved := template.UnresolvedPlaceholders(vars)
+	if len(unresolved) == 0 {
+		return nil
+	}
+	return fmt.Errorf("strict mode: unresolved placeholders %s, set them with --var or give them an inline default", strings.Join(unresolved, ", "))
+}
+
+// prepareCall merges the variables, resolves the API key and builds the provider chain for a template
+// cliVars holds the variables from the command line, they are not modified
+func prepareCall(cmd *cobra.Command, name string, template *templates.Template, cliVars map[string]string, jsonVars map[string]bool) (*preparedCall, error) {
+	// Named paths produce a JSON object, so the output must be requested as JSON
+	if len(template.Response.Paths) > 0 && formatFlag != formatJSON {
+		return nil, fmt.Errorf("the template extracts several fields with response.paths, use --format json")
+	}
+	if err := checkOutputDir(template); err != nil {
+		return nil, err
+	}
+
+	// Merge default variables: --var > template defaults > config defaults
+	replaceVars := mergeVariables(cfg.GetVariableDefaults(), template.Defaults, cliVars)
+	template.SetJSONVariables(jsonVars)
+
+	apiKeys, keyEntry, err := getAPIKeys(apiKeyFlag, apiKeyFileFlag, apiKeyCmdFlag, cfg, template)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+	apiKeys, err = selectAPIKeys(apiKeys, keyEntry, keyStrategyFlag)
+	if err != nil {
+		return nil, err
+	}
+	var apiKey string
+	if len(apiKeys) > 0 {
+		apiKey = apiKeys[0]
+	}
+
+	// Merge provider defaults from config, template values take precedence
+	template.ApplyProviderDefaults(cfg.GetProviderDefaults(template.Provider))
+
+	// Apply convenience parameter flags to the body, adding fields the template lacks
+	if err := applyModelFlag(cmd, template, replaceVars); err != nil {
+		return nil, err
+	}
+	if err := applyBodyParameterFlags(cmd, template); err != nil {
+		return nil, err
+	}
+	if streamFlag {
+		if err := applyStreamFlag(template); err != nil {
+			return nil, err
+		}
+	}
+
+	// Add api_key to replacement variables if not empty
 	if apiKey != "" {
 		replaceVars["api_key"] = apiKey
 	}
 
-	// Replace variables if needed
-	if len(replaceVars) > 0 {
-		template.ReplaceVariables(replaceVars)
+	// In strict mode placeholders that would be sent literally abort the call
+	if isStrict(cmd) {
+		if err := checkUnresolvedPlaceholders(template, replaceVars); err != nil {
+			return nil, withStage(stageVariables, err)
+		}
 	}
 
 	// Get the provider
-	provider, err := llm.GetProvider(template, apiKey)
+	signingSecret, err := getSigningSecret(cfg, template)
 	if err != nil {
-		return fmt.Errorf("failed to get provider: %w", err)
+		return nil, err
 	}
+	resolvedSecrets = append(resolvedSecrets, apiKeys...)
+	resolvedSecrets = append(resolvedSecrets, signingSecret)
 
-	// Call the provider
-	result, err := provider.Call(template)
+	clientOpts := llm.ClientOptions{
+		ProxyURL:         proxyFlag,
+		CACertFile:       caCertFlag,
+		Insecure:         insecureFlag,
+		MaxResponseBytes: maxResponseFlag,
+		SigningSecret:    signingSecret,
+		UserAgent:        userAgentFlag,
+		Context:          cmd.Context(),
+	}
+	// Keep a connection per concurrent request open so batch and repeat jobs reuse them
+	clientOpts.MaxIdleConnsPerHost = maxIdleConnsFlag
+	if clientOpts.MaxIdleConnsPerHost == 0 && concurrencyFlag > http.DefaultMaxIdleConnsPerHost {
+		clientOpts.MaxIdleConnsPerHost = concurrencyFlag
+	}
+	if verboseFlag {
+		clientOpts.Logger = log.New(os.Stderr, "[verbose] ", 0)
+	}
+	if streamFlag {
+		clientOpts.OnDelta = func(delta string) {
+			os.Stdout.WriteString(delta)
+		}
+	}
+	if !noRateLimitFlag {
+		clientOpts.RateLimiter, err = newRateLimiter(template.Provider)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var provider llm.Provider
+	if len(apiKeys) > 1 {
+		// Fail over to the next key when a key is rejected or rate limited
+		provider, err = newFailoverProvider(apiKeys, func(key string) (llm.Provider, error) {
+			return llm.GetProvider(template, key, clientOpts)
+		})
+	} else {
+		provider, err = llm.GetProvider(template, apiKey, clientOpts)
+	}
 	if err != nil {
-		return fmt.Errorf("LLM call failed: %w", err)
+		return nil, fmt.Errorf("failed to get provider: %w", err)
 	}
 
-	// Output result
-	if outputFlag == "" {
-		fmt.Print(result)
-	} else {
-		err = os.WriteFile(outputFlag, []byte(result), utils.GetFilePermissions())
+	// Pass results through the post hook command
+	if postHookFlag != "" {
+		provider = &postHookProvider{provider: provider, command: postHookFlag, timeout: postHookTimeout}
+	}
+
+	// Record every call in the audit log if one is configured
+	logFile := logFlag
+	if logFile == "" {
+		logFile = cfg.GetString(config.KeyLogFile)
+	}
+	if logFile != "" {
+		provider, err = newAuditProvider(provider, logFile, name, apiKey)
 		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &preparedCall{
+		name:       name,
+		template:   template,
+		vars:       replaceVars,
+		provider:   provider,
+		apiKey:     apiKey,
+		clientOpts: clientOpts,
+	}, nil
+}
+
+// validateStreamFlags checks that --stream is combined only with a single call whose content goes to stdout
+// The content is printed as it arrives, so it cannot be formatted, post-processed or written to files
+func validateStreamFlags() error {
+	for _, target := range outputFlags {
+		if target != "-" {
+			return fmt.Errorf("--stream prints to stdout, it cannot be combined with --output files")
+		}
+	}
+	switch {
+	case outputDirFlag != "":
+		return fmt.Errorf("--stream cannot be combined with --output-dir")
+	case outputTmplFlag != "":
+		return fmt.Errorf("--stream cannot be combined with --output-template")
+	case postHookFlag != "":
+		return fmt.Errorf("--stream cannot be combined with --post-hook")
+	case formatFlag == formatJSON:
+		return fmt.Errorf("--stream cannot be combined with --format json")
+	case len(fallbackFlags) > 0:
+		return fmt.Errorf("--stream cannot be combined with --fallback")
+	case reprocessFlag != "":
+		return fmt.Errorf("--stream cannot be combined with --reprocess")
+	}
+	return nil
+}
+
+// applyStreamFlag asks the API for an event stream with a final usage chunk
+// Only a single text content can be printed as it arrives, so templates that select or transform it are rejected
+func applyStreamFlag(template *templates.Template) error {
+	response := template.Response
+	if response.IsRaw() || len(response.Paths) > 0 || response.Mode != "" || response.Expr != "" {
+		return fmt.Errorf("--stream cannot be used with response.paths, response.mode, response.expr or a %s/%s response", templates.ResponseTypeText, templates.ResponseTypeBinary)
+	}
+	if len(response.Transform) > 0 {
+		return fmt.Errorf("--stream cannot be used with response.transform, the content is printed before it could be transformed")
+	}
+	if template.Pagination != nil {
+		return fmt.Errorf("--stream cannot be used with a paginated template")
+	}
+	if template.Request.BodyType != "" && template.Request.BodyType != templates.BodyTypeJSON {
+		return fmt.Errorf("--stream requires a JSON request body, the template has request.body_type %s", template.Request.BodyType)
+	}
+	if template.Engine != templates.EngineGoTemplate && template.Request.Body == nil {
+		return fmt.Errorf("--stream requires a request body, the %s request has none", template.Request.Method)
+	}
+	template.OverrideBodyParameter("stream", true)
+	template.OverrideBodyParameter("stream_options.include_usage", true)
+	return nil
+}
+
+// maxTokensFields are body fields limiting the generated tokens, in the order --max-tokens looks for them
+// Bodies without any of them get max_tokens
+var maxTokensFields = []string{"max_tokens", "max_completion_tokens", "max_output_tokens", "num_predict"}
+
+// applyModelFlag sets the model given with --model in the body field named by --model-path, or else
+// in the body's top-level "model" field and the model variable, whichever the template has
+func applyModelFlag(cmd *cobra.Command, template *templates.Template, vars map[string]string) error {
+	if !cmd.Flags().Changed("model") {
+		if cmd.Flags().Changed("model-path") {
+			return fmt.Errorf("--model-path requires --model")
+		}
+		return nil
+	}
+	if modelFlag == "" {
+		return fmt.Errorf("--model cannot be empty")
+	}
+
+	// The path is relative to the body, a leading "body." as in request.body is accepted
+	if path := strings.TrimPrefix(modelPathFlag, "body."); path != "" {
+		if template.Engine != templates.EngineGoTemplate && template.Request.Body == nil {
+			return fmt.Errorf("--model-path cannot be used, the %s request has no body", template.Request.Method)
+		}
+		template.OverrideBodyParameter(path, modelFlag)
+		return nil
+	}
+
+	applied := false
+	if _, ok := template.Request.Body["model"]; ok {
+		template.OverrideBodyParameter("model", modelFlag)
+		applied = true
+	}
+	// A {{model}} placeholder may also sit outside the body, e.g. in a Gemini URL
+	for _, name := range template.Placeholders() {
+		if name == "model" {
+			vars["model"] = modelFlag
+			applied = true
+		}
+	}
+	if !applied {
+		return fmt.Errorf("the template has no 'model' body field or {{model}} placeholder, use --model-path to name the body field")
+	}
+	return nil
+}
+
+// applyBodyParameterFlags sets the body fields given with --temperature, --max-tokens and --seed
+func applyBodyParameterFlags(cmd *cobra.Command, template *templates.Template) error {
+	params := make(map[string]interface{})
+	if cmd.Flags().Changed("temperature") {
+		params["temperature"] = temperatureFlag
+	}
+	if cmd.Flags().Changed("max-tokens") {
+		if maxTokensFlag < 1 {
+			return fmt.Errorf("--max-tokens must be at least 1, got %d", maxTokensFlag)
+		}
+		field := maxTokensFields[0]
+		for _, name := range maxTokensFields {
+			if _, ok := template.Request.Body[name]; ok {
+				field = name
+				break
+			}
+		}
+		params[field] = maxTokensFlag
+	}
+	if cmd.Flags().Changed("seed") {
+		params["seed"] = seedFlag
+	}
+	if len(params) == 0 {
+		return nil
+	}
+
+	if template.Engine != templates.EngineGoTemplate && template.Request.Body == nil {
+		fmt.Fprintf(os.Stderr, "Warning: body parameter flags are ignored, the %s request has no body\n", template.Request.Method)
+		return nil
+	}
+	for key, value := range params {
+		template.OverrideBodyParameter(key, value)
+	}
+	return nil
+}
+
+// isFallbackError reports whether a failed call should be retried with a fallback template
+// Network errors and error statuses qualify; a successful response whose content could not be extracted does not
+func isFallbackError(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var responseErr *llm.ResponseError
+	if errors.As(err, &responseErr) {
+		return responseErr.StatusCode < 200 || responseErr.StatusCode > 299
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+// outputResult checks the result of a call, prints the usage if requested and writes the content to the outputs
+func outputResult(result *llm.Result, templateName string, template *templates.Template) error {
+	if failOnEmptyFlag {
+		if err := checkEmptyResult(result); err != nil {
+			return withStage(stageExtraction, err)
+		}
+	}
+
+	// Streamed content has been printed already, the usage follows on its own line
+	if streamFlag && showUsageFlag && !strings.HasSuffix(result.Content, "\n") {
+		fmt.Fprintln(os.Stderr)
+	}
+
+	if showUsageFlag {
+		if result.Usage != nil {
+			printUsage(*result.Usage, template.Pricing)
+		} else {
+			fmt.Fprintln(os.Stderr, "Usage: not reported by the API")
+		}
+	}
+
+	if streamFlag {
+		return nil
+	}
+
+	// Files extracted by the files response mode are each written to their own file
+	if outputDirFlag != "" {
+		return withStage(stageOutput, writeResponseFiles(result.Content, outputDirFlag, outputPatternFlag))
+	}
+
+	// Binary responses are written as the raw body bytes
+	output := []byte(result.Content)
+	if template.Response.Type == templates.ResponseTypeBinary {
+		if outputTmplFlag != "" {
+			return withStage(stageOutput, fmt.Errorf("--output-template cannot format a binary response"))
+		}
+		output = result.Body
+	} else if outputTmplFlag != "" {
+		output = []byte(applyOutputTemplate(outputTmplFlag, result.Content, templateName))
+	}
+	return withStage(stageOutput, writeOutput(output, outputFlags, appendFlag))
+}
+
+// outputTemplateEscapes unescapes the sequences of an --output-template typed on a single line
+var outputTemplateEscapes = strings.NewReplacer(`\\`, `\`, `\n`, "\n", `\t`, "\t")
+
+// applyOutputTemplate formats the content of a call with --output-template
+// Substituted values are never rescanned, so placeholders in the model output are left intact
+func applyOutputTemplate(format, content, templateName string) string {
+	return templates.ReplaceVariablesInText(outputTemplateEscapes.Replace(format), map[string]string{
+		"content":   content,
+		"template":  templateName,
+		"timestamp": time.Now().Format(time.RFC3339),
+	})
+}
+
+// reprocessResponse extracts the result from a response body saved in a file
+func reprocessResponse(path string, template *templates.Template) (*llm.Result, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read saved response: %w", err)
+	}
+
+	result, err := llm.ProcessResponse(template, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reprocess %s: %w", path, err)
+	}
+	return result, nil
+}
+
+// saveRawResponse writes the raw response body of a call to path
+// When the call failed after the API responded (e.g. extraction failed), the body of the failed response is saved
+func saveRawResponse(path string, result *llm.Result, callErr error) error {
+	var body []byte
+	if result != nil {
+		body = result.Body
+	} else {
+		var responseErr *llm.ResponseError
+		if !errors.As(callErr, &responseErr) {
+			return nil
+		}
+		body = responseErr.Body
+	}
+
+	if err := writeOutputFile(path, body, false); err != nil {
+		return fmt.Errorf("failed to save raw response: %w", err)
+	}
+	infof(os.Stderr, "Raw response saved to %s\n", path)
+	return nil
+}
+
+// checkEmptyResult fails for a result whose content is empty or only whitespace
+// The raw response is included so the wrong field or an empty completion can be spotted
+func checkEmptyResult(result *llm.Result) error {
+	if strings.TrimSpace(result.Content) != "" {
+		return nil
+	}
+
+	raw := "(empty response body)"
+	if result.Response != nil {
+		if data, err := json.MarshalIndent(result.Response, "", "  "); err == nil {
+			raw = string(data)
+		}
+	}
+	return fmt.Errorf("extracted content is empty (--fail-on-empty), raw response:\n%s", raw)
+}
+
+// printUsage prints token usage and the estimated cost to stderr
+func printUsage(usage llm.Usage, pricing *templates.PricingConfig) {
+	fmt.Fprintf(os.Stderr, "Usage: prompt_tokens=%d completion_tokens=%d total_tokens=%d\n",
+		usage.PromptTokens, usage.CompletionTokens, usage.TotalTokens)
+
+	if pricing != nil {
+		currency := pricing.Currency
+		if currency == "" {
+			currency = "USD"
+		}
+		fmt.Fprintf(os.Stderr, "Estimated cost: %.6f %s\n", pricing.EstimateCost(usage.PromptTokens, usage.CompletionTokens), currency)
+	}
+}
+
+// writeOutput writes the result to every output target, '-' or no target meaning stdout
+func writeOutput(result []byte, targets []string, appendMode bool) error {
+	if len(targets) == 0 {
+		targets = []string{"-"}
+	}
+
+	// Keep stdout clean for the result when it is one of the targets
+	toStdout := false
+	for _, target := range targets {
+		if target == "-" {
+			toStdout = true
+		}
+	}
+	status := os.Stdout
+	if toStdout {
+		status = os.Stderr
+	}
+
+	for _, target := range targets {
+		if target == "-" {
+			if _, err := os.Stdout.Write(result); err != nil {
+				return fmt.Errorf("failed to write output: %w", err)
+			}
+			continue
+		}
+
+		if err := writeOutputFile(target, result, appendMode); err != nil {
 			return fmt.Errorf("failed to write output to file: %w", err)
 		}
-		fmt.Printf("Result saved to %s\n", outputFlag)
+		infof(status, "Result saved to %s\n", target)
 	}
 	return nil
 }
 
+// writeOutputFile writes or appends content to a file, creating it if needed
+// A written file is replaced atomically, so an interrupted or failed write leaves the previous content intact
+func writeOutputFile(path string, content []byte, appendMode bool) error {
+	if !appendMode {
+		return utils.WriteFileAtomic(path, content)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, utils.GetFilePermissions())
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(content); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// loadCLIVariables loads variables from the var file and --var flags, --var taking precedence
+// It also returns which variables are of the json type
+func loadCLIVariables(varFile string, varFlags []string, baseDir string) (map[string]string, map[string]bool, error) {
+	vars := make(map[string]string)
+	jsonVars := make(map[string]bool)
+	if varFile != "" {
+		fileVars, fileJSONVars, err := loadVarFile(varFile, baseDir)
+		if err != nil {
+			return nil, nil, err
+		}
+		vars = mergeVariables(vars, fileVars)
+		for name, isJSON := range fileJSONVars {
+			jsonVars[name] = isJSON
+		}
+	}
+
+	// Parse var flags with improved format support
+	flagVars, flagJSONVars, err := parseVarFlags(varFlags, baseDir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse var flags: %w", err)
+	}
+	vars = mergeVariables(vars, flagVars)
+	for name, isJSON := range flagJSONVars {
+		jsonVars[name] = isJSON
+	}
+	return vars, jsonVars, nil
+}
+
+// stdinConsumers lists the flags that read stdin, which a template read from stdin cannot share
+func stdinConsumers() []string {
+	var consumers []string
+	for _, varFlag := range varFlags {
+		parts := strings.SplitN(varFlag, ":", 3)
+		if len(parts) < 2 {
+			continue
+		}
+		value := parts[len(parts)-1]
+		if value == "-" || (len(parts) == 3 && parts[1] == varTypeJSON && value == jsonFilePrefix+"-") {
+			consumers = append(consumers, "--var "+varFlag)
+		}
+	}
+	if inputFlag == "-" {
+		consumers = append(consumers, "--input -")
+	}
+	if batchFlag == "-" {
+		consumers = append(consumers, "--batch -")
+	}
+	return consumers
+}
+
+// applyInputFlag reads the --input file into the variable named by --input-var
+// A --var flag for the same variable is an error, a --var-file value is overridden
+func applyInputFlag(vars map[string]string, jsonVars map[string]bool) error {
+	if inputVarFlag == "" {
+		return fmt.Errorf("--input-var cannot be empty")
+	}
+	for _, varFlag := range varFlags {
+		if strings.SplitN(varFlag, ":", 2)[0] == inputVarFlag {
+			return fmt.Errorf("variable '%s' is set by both --input and --var", inputVarFlag)
+		}
+	}
+
+	// The input is always read from the file or stdin, text and file only differ for --var values
+	varType := "file"
+	switch inputTypeFlag {
+	case "text", "file":
+	case varTypeBase64File:
+		varType = varTypeBase64File
+	default:
+		return fmt.Errorf("unsupported --input-type '%s', supported types: text, file, %s", inputTypeFlag, varTypeBase64File)
+	}
+
+	content, err := loadVariableValue(inputVarFlag, varType, inputFlag, varBaseDirFlag)
+	if err != nil {
+		return err
+	}
+	vars[inputVarFlag] = content
+	jsonVars[inputVarFlag] = false
+	return nil
+}
+
 // parseVarFlags parses --var flags with improved format support
-func parseVarFlags(varFlags []string) (map[string]string, error) {
+func parseVarFlags(varFlags []string, baseDir string) (map[string]string, map[string]bool, error) {
 	replaceVars := make(map[string]string)
+	jsonVars := make(map[string]bool)
 
 	for _, varFlag := range varFlags {
 		// Support both name:value and name:type:value formats
 		parts := strings.SplitN(varFlag, ":", 3)
 		if len(parts) < 2 {
-			return nil, fmt.Errorf("invalid var format, expected name:value or name:type:value: %s", varFlag)
+			return nil, nil, fmt.Errorf("invalid var format, expected name:value or name:type:value: %s", varFlag)
 		}
 
 		name := parts[0]
 		if name == "" {
-			return nil, fmt.Errorf("variable name cannot be empty in: %s", varFlag)
+			return nil, nil, fmt.Errorf("variable name cannot be empty in: %s", varFlag)
 		}
 
 		// Default to text type if only name:value provided
@@ -216,96 +1219,445 @@ func parseVarFlags(varFlags []string) (map[string]string, error) {
 			value = parts[2]
 		}
 
-		switch varType {
-		case "text":
-			if value == "-" {
-				stdinContent, err := io.ReadAll(os.Stdin)
-				if err != nil {
-					return nil, fmt.Errorf("failed to read from stdin for variable %s: %w", name, err)
-				}
-				replaceVars[name] = string(stdinContent)
-			} else {
-				replaceVars[name] = value
+		content, err := loadVariableValue(name, varType, value, baseDir)
+		if err != nil {
+			return nil, nil, err
+		}
+		replaceVars[name] = content
+		jsonVars[name] = isJSONVarType(varType)
+	}
+
+	return replaceVars, jsonVars, nil
+}
+
+// mergeVariables merges variable maps, later maps taking precedence
+func mergeVariables(layers ...map[string]string) map[string]string {
+	merged := make(map[string]string)
+	for _, layer := range layers {
+		for name, value := range layer {
+			merged[name] = value
+		}
+	}
+	return merged
+}
+
+// resolveVarPath resolves a relative file variable path against baseDir, or the working directory if empty
+func resolveVarPath(path, baseDir string) string {
+	if baseDir == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(baseDir, path)
+}
+
+// loadVariableValue resolves a variable value according to its type
+// Relative file paths are resolved against baseDir if set
+func loadVariableValue(name, varType, value, baseDir string) (string, error) {
+	switch varType {
+	case "text":
+		if value == "-" {
+			stdinContent, err := io.ReadAll(os.Stdin)
+			if err != nil {
+				return "", fmt.Errorf("failed to read from stdin for variable %s: %w", name, err)
 			}
-		case "file":
-			var content []byte
+			return string(stdinContent), nil
+		}
+		return value, nil
+	case "file":
+		content, err := loadFileVariable(name, value, baseDir)
+		if err != nil {
+			return "", err
+		}
+		return string(content), nil
+
+	case varTypeBase64File:
+		// The bytes are encoded as read, binary content is never converted to a string first
+		content, err := loadFileVariable(name, value, baseDir)
+		if err != nil {
+			return "", err
+		}
+		return base64.StdEncoding.EncodeToString(content), nil
+
+	case varTypeJSON:
+		// The value is a JSON literal, or file:<path> / '-' to read the JSON document
+		content := value
+		if value == "-" || strings.HasPrefix(value, jsonFilePrefix) {
 			var err error
-			if value == "-" {
-				// Read raw content from stdin
-				content, err = io.ReadAll(os.Stdin)
-				if err != nil {
-					return nil, fmt.Errorf("failed to read from stdin for variable %s: %w", name, err)
-				}
-			} else {
-				// Read raw content from file path
-				if value == "" {
-					return nil, fmt.Errorf("file path cannot be empty for variable %s", name)
-				}
-				content, err = os.ReadFile(value)
-				if err != nil {
-					return nil, fmt.Errorf("failed to read file %s for variable %s: %w", value, name, err)
-				}
+			content, err = loadVariableValue(name, "file", strings.TrimPrefix(value, jsonFilePrefix), baseDir)
+			if err != nil {
+				return "", err
 			}
-			replaceVars[name] = string(content)
+		}
+		if !json.Valid([]byte(content)) {
+			return "", fmt.Errorf("invalid JSON value for variable %s", name)
+		}
+		return strings.TrimSpace(content), nil
+
+	case varTypeCSV:
+		content, err := loadFileVariable(name, value, baseDir)
+		if err != nil {
+			return "", err
+		}
+		return csvToJSON(name, content)
+
+	default:
+		return "", fmt.Errorf("unsupported variable type '%s' for variable %s, supported types: text, file, base64file, json, csv", varType, name)
+	}
+}
 
+// isJSONVarType reports whether variables of a type hold a JSON document that is spliced into the body structurally
+func isJSONVarType(varType string) bool {
+	return varType == varTypeJSON || varType == varTypeCSV
+}
+
+// csvToJSON converts CSV content to a JSON array with an object per row, keyed by the header row
+// Values stay strings; every row must have as many fields as the header
+func csvToJSON(name string, content []byte) (string, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))))
+	header, err := reader.Read()
+	if err == io.EOF {
+		return "", fmt.Errorf("CSV for variable %s is empty, expected a header row", name)
+	}
+	if err != nil {
+		return "", fmt.Errorf("invalid CSV for variable %s: %w", name, err)
+	}
+	for i, key := range header {
+		if strings.TrimSpace(key) == "" {
+			return "", fmt.Errorf("invalid CSV for variable %s: header column %d has no name", name, i+1)
+		}
+	}
+
+	rows := []map[string]string{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("invalid CSV for variable %s: %w", name, err)
+		}
+		row := make(map[string]string, len(header))
+		for i, key := range header {
+			row[key] = record[i]
+		}
+		rows = append(rows, row)
+	}
+
+	data, err := json.Marshal(rows)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode CSV rows of variable %s: %w", name, err)
+	}
+	return string(data), nil
+}
+
+// loadFileVariable reads the raw content of a file variable from a file path, or from stdin for '-'
+func loadFileVariable(name, value, baseDir string) ([]byte, error) {
+	if value == "-" {
+		content, err := readFileVariable(name, "stdin", os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read from stdin for variable %s: %w", name, err)
+		}
+		return content, nil
+	}
+
+	if value == "" {
+		return nil, fmt.Errorf("file path cannot be empty for variable %s", name)
+	}
+	file, err := os.Open(resolveVarPath(value, baseDir))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s for variable %s: %w", value, name, err)
+	}
+	defer file.Close()
+	content, err := readFileVariable(name, value, file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s for variable %s: %w", value, name, err)
+	}
+	return content, nil
+}
+
+// defaultMaxVarBytes is the file variable size above which a warning is printed
+const defaultMaxVarBytes = 1 << 20
+
+// readFileVariable reads the content of a file variable, checking it against --max-var-bytes
+// With --fail-on-large-var at most one byte past the limit is read before failing
+func readFileVariable(name, source string, reader io.Reader) ([]byte, error) {
+	if maxVarBytesFlag > 0 && failOnLargeVarFlag {
+		reader = io.LimitReader(reader, maxVarBytesFlag+1)
+	}
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	if maxVarBytesFlag <= 0 || int64(len(content)) <= maxVarBytesFlag {
+		return content, nil
+	}
+
+	if failOnLargeVarFlag {
+		return nil, fmt.Errorf("content is larger than --max-var-bytes (%d bytes)", maxVarBytesFlag)
+	}
+	fmt.Fprintf(os.Stderr, "Warning: variable %s from %s is %d bytes, larger than --max-var-bytes (%d bytes); it is sent in full\n", name, source, len(content), maxVarBytesFlag)
+	return content, nil
+}
+
+// loadVarFile loads variables from a JSON or YAML file
+// Values are text by default; an object {"type": "file", "value": "./x.png"} selects the variable type
+func loadVarFile(filePath, baseDir string) (map[string]string, map[string]bool, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read var file %s: %w", filePath, err)
+	}
+
+	var raw map[string]interface{}
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	default:
+		err = json.Unmarshal(data, &raw)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse var file %s: %w", filePath, err)
+	}
+
+	vars := make(map[string]string, len(raw))
+	jsonVars := make(map[string]bool)
+	for name, entry := range raw {
+		if name == "" {
+			return nil, nil, fmt.Errorf("variable name cannot be empty in var file %s", filePath)
+		}
+
+		var varType, value string
+		switch v := entry.(type) {
+		case map[string]interface{}:
+			varType, _ = v["type"].(string)
+			if varType == "" {
+				varType = "text"
+			}
+			if varType == varTypeJSON {
+				// Structured values are used as the JSON document directly
+				if _, isString := v["value"].(string); !isString {
+					data, err := json.Marshal(v["value"])
+					if err != nil {
+						return nil, nil, fmt.Errorf("invalid JSON value for variable %s in var file %s: %w", name, filePath, err)
+					}
+					value = string(data)
+					break
+				}
+			}
+			if v["value"] != nil {
+				value = scalarString(v["value"])
+			}
+		case []interface{}, nil:
+			return nil, nil, fmt.Errorf("invalid value for variable %s in var file %s, expected a string or {\"type\": ..., \"value\": ...}", name, filePath)
 		default:
-			return nil, fmt.Errorf("unsupported variable type '%s' for variable %s, supported types: text, file", varType, name)
+			varType = "text"
+			value = scalarString(v)
 		}
+
+		content, err := loadVariableValue(name, varType, value, baseDir)
+		if err != nil {
+			return nil, nil, err
+		}
+		vars[name] = content
+		jsonVars[name] = isJSONVarType(varType)
+	}
+
+	return vars, jsonVars, nil
+}
+
+// scalarString formats a decoded JSON or YAML scalar as a string
+func scalarString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprintf("%v", v)
 	}
+}
+
+// apiKeyCommandPrefix marks secret file values that are commands printing the key
+const apiKeyCommandPrefix = "cmd:"
 
-	return replaceVars, nil
+// apiKeyCommandTimeout bounds how long an API key command may run
+const apiKeyCommandTimeout = 15 * time.Second
+
+// getAPIKey retrieves API key based on priority: CLI > CLI key file > CLI command > secret file > environment
+// Secret file entries with several keys return the first one
+func getAPIKey(cliAPIKey, cliAPIKeyFile, cliAPIKeyCmd string, cfg *config.Config, template *templates.Template) (string, error) {
+	keys, _, err := getAPIKeys(cliAPIKey, cliAPIKeyFile, cliAPIKeyCmd, cfg, template)
+	if err != nil || len(keys) == 0 {
+		return "", err
+	}
+	return keys[0], nil
 }
 
-// getAPIKey retrieves API key based on priority: CLI > file > environment
-func getAPIKey(cliAPIKey string, cfg *config.Config, template *templates.Template) (string, error) {
+// getAPIKeys retrieves the candidate API keys based on priority: CLI > CLI key file > CLI command > secret file > environment
+// Only secret file entries can hold several keys; the name of the entry is returned with them
+func getAPIKeys(cliAPIKey, cliAPIKeyFile, cliAPIKeyCmd string, cfg *config.Config, template *templates.Template) ([]string, string, error) {
 	// 1. CLI argument has highest priority
 	if cliAPIKey != "" {
-		return cliAPIKey, nil
+		return []string{cliAPIKey}, "", nil
+	}
+
+	// 2. Key file given on the CLI, e.g. a mounted secret
+	if cliAPIKeyFile != "" {
+		key, err := readAPIKeyFile(cliAPIKeyFile)
+		if err != nil {
+			return nil, "", err
+		}
+		return []string{key}, "", nil
 	}
 
-	// 2. Try to load from secret file
+	// 3. Command given on the CLI, e.g. a password manager
+	if cliAPIKeyCmd != "" {
+		key, err := runAPIKeyCommand(cliAPIKeyCmd)
+		if err != nil {
+			return nil, "", err
+		}
+		return []string{key}, "", nil
+	}
+
+	// 4. Try to load from secret file
 	apiKeysFile := cfg.GetString(config.KeySecretFile)
 	if apiKeysFile != "" {
 		if keys, err := loadApiKeys(apiKeysFile); err == nil {
+			keyNames := []string{"api_key", "default_api_key"}
 			// Try provider-specific key first
 			if template.Provider != "" {
-				if key, ok := keys[template.Provider+"_api_key"]; ok && key != "" {
-					return key, nil
-				}
+				keyNames = append([]string{template.Provider + "_api_key"}, keyNames...)
 			}
-			// Try generic keys
-			for _, keyName := range []string{"api_key", "default_api_key"} {
-				if key, ok := keys[keyName]; ok && key != "" {
-					return key, nil
+			for _, keyName := range keyNames {
+				values := nonEmpty(keys[keyName])
+				if len(values) == 0 {
+					continue
+				}
+				resolved := make([]string, len(values))
+				for i, value := range values {
+					if resolved[i], err = resolveSecretValue(value); err != nil {
+						return nil, "", err
+					}
 				}
+				return resolved, keyName, nil
 			}
 		}
 	}
 
-	// 3. Try environment variables
-	envKeys := []string{"API_KEY"}
+	// 5. Try environment variables
+	for _, envKey := range apiKeyEnvNames(template) {
+		if envValue := utils.GetEnvironmentVariableCaseInsensitive(envKey); envValue != "" {
+			return []string{envValue}, "", nil
+		}
+	}
+
+	// API key is optional - return no keys if none is found
+	return nil, "", nil
+}
+
+// readAPIKeyFile returns the trimmed first line of a file holding an API key
+func readAPIKeyFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read API key file: %w", err)
+	}
+	firstLine, _, _ := strings.Cut(string(data), "\n")
+	key := strings.TrimSpace(firstLine)
+	if key == "" {
+		return "", fmt.Errorf("API key file %s is empty", path)
+	}
+	return key, nil
+}
+
+// apiKeyEnvNames returns the environment variables checked for the API key, in order:
+// the template's api_key_env names, then <PROVIDER>_API_KEY and API_KEY
+func apiKeyEnvNames(template *templates.Template) []string {
+	names := append([]string(nil), template.APIKeyEnv...)
 	if template.Provider != "" {
-		envKeys = append([]string{strings.ToUpper(template.Provider) + "_API_KEY"}, envKeys...)
+		names = append(names, strings.ToUpper(template.Provider)+"_API_KEY")
 	}
+	return append(names, "API_KEY")
+}
 
-	for _, envKey := range envKeys {
-		if envValue := utils.GetEnvironmentVariableCaseInsensitive(envKey); envValue != "" {
-			return envValue, nil
+// getSigningSecret returns the secret of the template's signing block from the secret file
+// It returns an empty string for templates that do not sign requests
+func getSigningSecret(cfg *config.Config, template *templates.Template) (string, error) {
+	if template.Signing == nil {
+		return "", nil
+	}
+
+	entry := template.Signing.SecretKeyRef
+	apiKeysFile := cfg.GetString(config.KeySecretFile)
+	if apiKeysFile == "" {
+		return "", fmt.Errorf("the template signs requests with secret '%s', but no secret file is configured", entry)
+	}
+	keys, err := loadApiKeys(apiKeysFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to load secret file: %w", err)
+	}
+	values := nonEmpty(keys[entry])
+	if len(values) == 0 {
+		return "", fmt.Errorf("signing secret '%s' not found in secret file %s", entry, apiKeysFile)
+	}
+	return resolveSecretValue(values[0])
+}
+
+// nonEmpty returns the non-empty values
+func nonEmpty(values []string) []string {
+	var result []string
+	for _, value := range values {
+		if value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// resolveSecretValue returns a secret file value, running it if it is a "cmd:" command
+func resolveSecretValue(value string) (string, error) {
+	if command, ok := strings.CutPrefix(value, apiKeyCommandPrefix); ok {
+		return runAPIKeyCommand(strings.TrimSpace(command))
+	}
+	return value, nil
+}
+
+// runAPIKeyCommand runs a command through the shell and returns its trimmed stdout as the API key
+func runAPIKeyCommand(command string) (string, error) {
+	if command == "" {
+		return "", fmt.Errorf("API key command is empty")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), apiKeyCommandTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	keyCmd := utils.ShellCommand(ctx, command)
+	keyCmd.Stdout = &stdout
+	keyCmd.Stderr = &stderr
+
+	if err := keyCmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("API key command timed out after %s", apiKeyCommandTimeout)
+		}
+		if detail := strings.TrimSpace(stderr.String()); detail != "" {
+			return "", fmt.Errorf("API key command failed: %w: %s", err, detail)
 		}
+		return "", fmt.Errorf("API key command failed: %w", err)
 	}
 
-	// API key is optional - return empty string if no key is found
-	return "", nil
+	key := strings.TrimSpace(stdout.String())
+	if key == "" {
+		return "", fmt.Errorf("API key command produced no output")
+	}
+	return key, nil
 }
 
 // loadApiKeys loads API keys from a JSON file
-func loadApiKeys(filePath string) (map[string]string, error) {
+func loadApiKeys(filePath string) (map[string]apiKeyValues, error) {
 	data, err := os.ReadFile(filePath)
 	if err != nil {
 		return nil, err
 	}
 
-	var keys map[string]string
+	var keys map[string]apiKeyValues
 	if err := json.Unmarshal(data, &keys); err != nil {
 		return nil, err
 	}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/nodewee/llm-caller/pkg/templates"
+)
+
+func TestApplyStreamFlag(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		wantErr  bool
+	}{
+		{"json body", `{"provider": "openai", "request": {"url": "https://api.example.com", "body": {"model": "m"}}}`, false},
+		{"text response", `{"provider": "openai", "request": {"url": "https://api.example.com", "body": {}}, "response": {"type": "text"}}`, true},
+		{"named paths", `{"provider": "openai", "request": {"url": "https://api.example.com", "body": {}}, "response": {"paths": {"a": "b"}}}`, true},
+		{"transform", `{"provider": "openai", "request": {"url": "https://api.example.com", "body": {}}, "response": {"transform": ["trim"]}}`, true},
+		{"form body", `{"provider": "openai", "request": {"url": "https://api.example.com", "body_type": "form", "body": {"a": "b"}}}`, true},
+		{"no body", `{"provider": "openai", "request": {"url": "https://api.example.com", "method": "GET"}}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template, err := templates.LoadTemplateFromJSON(tt.template)
+			if err != nil {
+				t.Fatalf("LoadTemplateFromJSON: %v", err)
+			}
+			err = applyStreamFlag(template)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("applyStreamFlag() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if _, err := template.ReplaceVariables(nil); err != nil {
+				t.Fatalf("ReplaceVariables: %v", err)
+			}
+			if template.Request.Body["stream"] != true {
+				t.Errorf("body stream = %v, want true", template.Request.Body["stream"])
+			}
+			options, _ := template.Request.Body["stream_options"].(map[string]interface{})
+			if options["include_usage"] != true {
+				t.Errorf("body stream_options = %v, want include_usage true", template.Request.Body["stream_options"])
+			}
+		})
+	}
+}
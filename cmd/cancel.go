@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+)
+
+// exitCodeCancelled is the exit status of a call interrupted with Ctrl-C, following the shell convention of 128+SIGINT
+const exitCodeCancelled = 130
+
+// errCancelled is returned when a call is interrupted, Execute reports it and exits with exitCodeCancelled
+var errCancelled = errors.New("cancelled")
+
+// interruptContext returns a context cancelled on the first interrupt (Ctrl-C), tearing down in-flight requests
+// Further interrupts get the default behaviour again, so a second Ctrl-C exits at once if something does not stop
+func interruptContext(parent context.Context) (context.Context, context.CancelFunc) {
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	go func() {
+		defer signal.Stop(signals)
+		select {
+		case <-signals:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, cancel
+}
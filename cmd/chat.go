@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/nodewee/llm-caller/pkg/llm"
+	"github.com/nodewee/llm-caller/pkg/templates"
+	"github.com/spf13/cobra"
+)
+
+// Chat session commands
+const (
+	chatCommandExit  = "/exit"
+	chatCommandReset = "/reset"
+)
+
+// Chat command flags
+var (
+	chatVarFlags        []string
+	chatVarFileFlag     string
+	chatVarBaseDirFlag  string
+	chatInputVarFlag    string
+	chatAPIKeyFlag      string
+	chatVerboseFlag     bool
+	chatProxyFlag       string
+	chatNoRateLimitFlag bool
+)
+
+var chatCmd = &cobra.Command{
+	Use:   "chat <template>",
+	Short: "Start an interactive chat session with a template",
+	Long: `Start an interactive chat session using a chat template.
+
+Each line you enter is passed to the template as the input variable (default 'prompt')
+and the extracted response is printed. The conversation history is kept between calls
+by inserting the previous user and assistant turns into the request body's 'messages'
+array, before the template's last message (the one carrying the input variable).
+
+The template's request body must contain a 'messages' array.
+
+Session commands:
+  /reset  Clear the conversation history
+  /exit   End the session (Ctrl-D also works)
+
+Examples:
+  llm-caller chat deepseek-chat
+  llm-caller chat deepseek-chat --var model:deepseek-reasoner
+  llm-caller chat my-template --input-var question`,
+	Args: cobra.ExactArgs(1),
+	RunE: runChat,
+}
+
+func init() {
+	chatCmd.Flags().StringArrayVar(&chatVarFlags, "var", []string{}, "Variable in 'name[:type]:value' format, applied to every call")
+	chatCmd.Flags().StringVar(&chatVarFileFlag, "var-file", "", "JSON or YAML file with variables; --var flags take precedence")
+	chatCmd.Flags().StringVar(&chatVarBaseDirFlag, "var-base-dir", "", "Directory relative file variable paths are resolved against (default: current directory)")
+	chatCmd.Flags().StringVar(&chatInputVarFlag, "input-var", "prompt", "Variable that receives each line of user input")
+	chatCmd.Flags().StringVar(&chatAPIKeyFlag, "api-key", "", "API key (optional, overrides config and environment)")
+	chatCmd.Flags().BoolVarP(&chatVerboseFlag, "verbose", "v", false, "Log request and response details to stderr (API key is redacted)")
+	chatCmd.Flags().BoolVar(&chatNoRateLimitFlag, "no-ratelimit", false, "Ignore the provider's configured rate limit (ratelimit.<provider>)")
+	chatCmd.Flags().StringVar(&chatProxyFlag, "proxy", "", "Proxy URL (http, https or socks5), overrides HTTP_PROXY/HTTPS_PROXY")
+}
+
+func runChat(cmd *cobra.Command, args []string) error {
+	replaceVars, jsonVars, err := loadCLIVariables(chatVarFileFlag, chatVarFlags, chatVarBaseDirFlag)
+	if err != nil {
+		return err
+	}
+
+	template, err := templates.LoadTemplate(cfg, args[0])
+	if err != nil {
+		return fmt.Errorf("failed to load template: %w", err)
+	}
+	warnTemplate(args[0], template)
+	if _, ok := template.Request.Body["messages"].([]interface{}); !ok {
+		return fmt.Errorf("chat requires a template whose request body has a 'messages' array")
+	}
+
+	replaceVars = mergeVariables(cfg.GetVariableDefaults(), template.Defaults, replaceVars)
+	template.SetJSONVariables(jsonVars)
+
+	apiKey, err := getAPIKey(chatAPIKeyFlag, "", "", cfg, template)
+	if err != nil {
+		return fmt.Errorf("failed to get API key: %w", err)
+	}
+	if apiKey != "" {
+		replaceVars["api_key"] = apiKey
+	}
+
+	template.ApplyProviderDefaults(cfg.GetProviderDefaults(template.Provider))
+
+	signingSecret, err := getSigningSecret(cfg, template)
+	if err != nil {
+		return err
+	}
+	clientOpts := llm.ClientOptions{ProxyURL: chatProxyFlag, SigningSecret: signingSecret}
+	if chatVerboseFlag {
+		clientOpts.Logger = log.New(os.Stderr, "[verbose] ", 0)
+	}
+	if !chatNoRateLimitFlag {
+		clientOpts.RateLimiter, err = newRateLimiter(template.Provider)
+		if err != nil {
+			return err
+		}
+	}
+	provider, err := llm.GetProvider(template, apiKey, clientOpts)
+	if err != nil {
+		return fmt.Errorf("failed to get provider: %w", err)
+	}
+
+	return chatLoop(os.Stdin, os.Stdout, provider, template, replaceVars)
+}
+
+// chatLoop reads user input line by line and keeps the conversation history between calls
+func chatLoop(in io.Reader, out io.Writer, provider llm.Provider, template *templates.Template, vars map[string]string) error {
+	var history []interface{}
+
+	fmt.Fprintf(out, "Chatting with %s. Type %s to clear the history, %s to quit.\n", template.Provider, chatCommandReset, chatCommandExit)
+
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+	for {
+		fmt.Fprint(out, "> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return scanner.Err()
+		}
+
+		input := strings.TrimSpace(scanner.Text())
+		switch input {
+		case "":
+			continue
+		case chatCommandExit:
+			return nil
+		case chatCommandReset:
+			history = nil
+			fmt.Fprintln(out, "Conversation history cleared.")
+			continue
+		}
+
+		turnVars := copyVars(vars)
+		turnVars[chatInputVarFlag] = input
+
+		userMessage, content, err := chatTurn(provider, template, turnVars, history)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			continue
+		}
+
+		fmt.Fprintln(out, content)
+		history = append(history, userMessage, map[string]interface{}{
+			"role":    "assistant",
+			"content": content,
+		})
+	}
+}
+
+// chatTurn sends one user turn with the previous history and returns the rendered user message and the reply
+func chatTurn(provider llm.Provider, template *templates.Template, vars map[string]string, history []interface{}) (interface{}, string, error) {
+	turn := template.Clone()
+
+	// Replace variables before inserting the history so earlier turns are never re-expanded
+	if _, err := turn.ReplaceVariables(vars); err != nil {
+		return nil, "", err
+	}
+
+	messages, _ := turn.Request.Body["messages"].([]interface{})
+	if len(messages) == 0 {
+		return nil, "", fmt.Errorf("the template's 'messages' array is empty")
+	}
+	last := len(messages) - 1
+	userMessage := messages[last]
+
+	withHistory := make([]interface{}, 0, len(messages)+len(history))
+	withHistory = append(withHistory, messages[:last]...)
+	withHistory = append(withHistory, history...)
+	withHistory = append(withHistory, userMessage)
+	turn.Request.Body["messages"] = withHistory
+
+	result, err := provider.Call(turn)
+	if err != nil {
+		return nil, "", fmt.Errorf("LLM call failed: %w", err)
+	}
+	return userMessage, result.Content, nil
+}
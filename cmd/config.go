@@ -1,10 +1,18 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/nodewee/llm-caller/pkg/config"
+	"github.com/nodewee/llm-caller/pkg/ratelimit"
+	"github.com/nodewee/llm-caller/pkg/utils"
 
 	"github.com/spf13/cobra"
 )
@@ -15,39 +23,65 @@ var configCmd = &cobra.Command{
 	Short: "Configure application settings",
 	Long: `Manage application configuration including template directory and API keys file.
 
-Configuration is stored in ~/.llm-caller/config.yaml
+Configuration is stored in ~/.llm-caller/config.yaml, or in
+~/.llm-caller/config.<profile>.yaml when a profile is selected with --profile
+or the LLM_CALLER_PROFILE environment variable.
 
 Usage:
   config [key]            Get the value for a specific key
   config [key] [value]    Set a value for a specific key
   config list             List all configuration values
+  config [key] --json     Get the value as JSON, also 'config list --json'
   config remove [key]     Remove a specific key (revert to default)
+  config secret ls        List API keys in the secret file (values masked)
+  config secret set [name] [value]  Add or update an API key in the secret file
+  config export           Write the configuration as a portable JSON bundle
+  config import [file]    Restore a configuration bundle
 
 Available settings:
-  template_dir - Directory where template files are stored
-  secret_file  - Path to JSON file containing API keys
+  template_dir                     - Directory where template files are stored
+  secret_file                      - Path to JSON file containing API keys
+  strict                           - Reject calls with unresolved {{variables}} (true or false)
+  save_history                     - Record every call for 'llm-caller history' (true or false)
+  provider.<name>.base_url         - Base URL prepended to relative template URLs
+  provider.<name>.headers.<header> - Default header sent by templates of this provider
+  default.<variable>               - Default variable value for all templates
+  ratelimit.<provider>             - Request rate limit for a provider, e.g. 3/s, 60/m or 1000/h
+  alias.<name>                     - Template name used when <name> is given as a template
+
+Provider defaults apply to templates whose "provider" field matches <name>.
+Values set in the template take precedence over provider defaults.
   
 Examples:
   llm-caller config template_dir               # Get value
   llm-caller config template_dir ~/my-templates # Set value
   llm-caller config list                       # List all settings
-  llm-caller config remove template_dir        # Remove setting (revert to default)`,
+  llm-caller config list --json                # List all settings as JSON
+  llm-caller config remove template_dir        # Remove setting (revert to default)
+  llm-caller config provider.openai.base_url https://api.openai.com/v1
+  llm-caller config provider.openai.headers.Authorization "Bearer {{api_key}}"
+  llm-caller config default.model gpt-4o
+  llm-caller config alias.dc deepseek-chat             # 'llm-caller call dc' uses deepseek-chat`,
 	Args: cobra.MaximumNArgs(2),
 	RunE: runConfig,
 }
 
 // Config subcommands
 var configListCmd = &cobra.Command{
-	Use:   "list",
-	Short: "List all configuration values",
-	Long:  `Display all current configuration values including file location.`,
-	Args:  cobra.NoArgs,
-	RunE:  runConfigList,
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List all configuration values",
+	Long: `Display all current configuration values including the active profile and file location.
+
+Use --json for machine-readable output.`,
+	Args: cobra.NoArgs,
+	RunE: runConfigList,
 }
 
 var configRemoveCmd = &cobra.Command{
-	Use:   "remove <key>",
-	Short: "Remove a configuration value",
+	Use:     "remove <key>",
+	Aliases: []string{"rm"},
+	Short:   "Remove a configuration value",
 	Long: `Remove a configuration value, reverting to default.
 
 Examples:
@@ -57,10 +91,128 @@ Examples:
 	RunE: runConfigRemove,
 }
 
+var configSecretCmd = &cobra.Command{
+	Use:   "secret",
+	Short: "Manage API keys in the secret file",
+	Long: `Manage API keys stored in the secret file configured with 'config secret_file'.
+
+The file is created with 0600 permissions if it does not exist.
+Key names follow the lookup used by 'call': <provider>_api_key, api_key or default_api_key.`,
+}
+
+var configSecretListCmd = &cobra.Command{
+	Use:     "ls",
+	Aliases: []string{"list"},
+	Short:   "List API key names with masked values",
+	Args:    cobra.NoArgs,
+	RunE:    runConfigSecretList,
+}
+
+var configSecretSetCmd = &cobra.Command{
+	Use:   "set <name> <value>",
+	Short: "Add or update an API key",
+	Long: `Add or update an API key in the secret file.
+
+Examples:
+  llm-caller config secret set deepseek_api_key sk-xxx
+  llm-caller config secret set api_key "$(cat key.txt)"`,
+	Args: cobra.ExactArgs(2),
+	RunE: runConfigSecretSet,
+}
+
+var configExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the configuration as a JSON bundle",
+	Long: `Write the effective configuration as a single JSON bundle, to move a setup to another machine.
+
+Paths inside the home directory are written as ~/... and expanded again on import.
+API keys from the secret file are only included with --include-secrets; the bundle
+is then written with owner-only permissions and must be kept private.
+
+Examples:
+  llm-caller config export > llm-caller.json
+  llm-caller config export -o llm-caller.json --include-secrets`,
+	Args: cobra.NoArgs,
+	RunE: runConfigExport,
+}
+
+var configImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import a configuration bundle",
+	Long: `Restore a bundle written by 'config export' into the active configuration, '-' reads it from stdin.
+
+Settings in the bundle overwrite existing ones, other settings are kept. API keys in
+the bundle are only written to the secret file with --include-secrets.
+
+Examples:
+  llm-caller config import llm-caller.json
+  llm-caller config import llm-caller.json --include-secrets`,
+	Args: cobra.ExactArgs(1),
+	RunE: runConfigImport,
+}
+
+// Config export/import flags
+var (
+	configJSONFlag    bool
+	exportOutputFlag  string
+	exportSecretsFlag bool
+	importSecretsFlag bool
+)
+
+// configListJSON is the --json output of 'config list'
+type configListJSON struct {
+	Profile    string                 `json:"profile,omitempty"`
+	ConfigFile string                 `json:"config_file"`
+	Settings   map[string]interface{} `json:"settings"`
+}
+
+// configValueJSON is the --json output of 'config <key>'
+type configValueJSON struct {
+	Key   string      `json:"key"`
+	Value interface{} `json:"value"`
+}
+
+// configBundleVersion is the format version of configuration bundles
+const configBundleVersion = 1
+
+// configBundle is the portable configuration written by 'config export'
+type configBundle struct {
+	Version int                     `json:"version"`
+	Config  map[string]interface{}  `json:"config"`
+	Secrets map[string]apiKeyValues `json:"secrets,omitempty"`
+}
+
 func init() {
 	// Config subcommands
 	configCmd.AddCommand(configListCmd)
 	configCmd.AddCommand(configRemoveCmd)
+	configCmd.AddCommand(configSecretCmd)
+	configCmd.AddCommand(configExportCmd)
+	configCmd.AddCommand(configImportCmd)
+
+	configSecretCmd.AddCommand(configSecretListCmd)
+	configSecretCmd.AddCommand(configSecretSetCmd)
+
+	configCmd.Flags().BoolVar(&configJSONFlag, "json", false, "Print the value of the key as JSON")
+	configListCmd.Flags().BoolVar(&configJSONFlag, "json", false, "Print the configuration as JSON")
+	configExportCmd.Flags().StringVarP(&exportOutputFlag, "output", "o", "", "Write the bundle to this file instead of stdout")
+	configExportCmd.Flags().BoolVar(&exportSecretsFlag, "include-secrets", false, "Include the API keys of the secret file in the bundle")
+	configImportCmd.Flags().BoolVar(&importSecretsFlag, "include-secrets", false, "Also write the API keys of the bundle to the secret file")
+}
+
+// validateConfigValue checks the value of keys that only accept a specific format
+func validateConfigValue(key, value string) error {
+	if key == config.KeyStrict || key == config.KeySaveHistory {
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("invalid value for %s: %s, expected true or false", key, value)
+		}
+	}
+	if strings.HasPrefix(key, config.KeyRateLimitPrefix+".") {
+		if _, err := ratelimit.ParseRate(value); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // Config command handler - unified get/set functionality
@@ -78,24 +230,25 @@ func runConfig(cmd *cobra.Command, args []string) error {
 		if value == nil {
 			return fmt.Errorf("key %s not found", key)
 		}
+		if configJSONFlag {
+			return printJSON(configValueJSON{Key: key, Value: value})
+		}
 		fmt.Println(value)
 		return nil
 	}
 
 	// If two arguments, set the value (former set command)
+	if configJSONFlag {
+		return fmt.Errorf("--json only applies when getting a value")
+	}
 	value := args[1]
 
 	// Validate key
-	validKeys := []string{config.KeyTemplateDir, config.KeySecretFile}
-	validKey := false
-	for _, vk := range validKeys {
-		if key == vk {
-			validKey = true
-			break
-		}
+	if err := config.ValidateKey(key); err != nil {
+		return err
 	}
-	if !validKey {
-		return fmt.Errorf("invalid key: %s, valid keys are: %s", key, strings.Join(validKeys, ", "))
+	if err := validateConfigValue(key, value); err != nil {
+		return err
 	}
 
 	if err := cfg.Set(key, value); err != nil {
@@ -108,6 +261,12 @@ func runConfig(cmd *cobra.Command, args []string) error {
 
 func runConfigList(cmd *cobra.Command, args []string) error {
 	configPath := cfg.GetConfigFilePath()
+	if configJSONFlag {
+		return printJSON(configListJSON{Profile: cfg.Profile(), ConfigFile: configPath, Settings: cfg.List()})
+	}
+	if cfg.Profile() != "" {
+		fmt.Printf("Active profile: %s\n", cfg.Profile())
+	}
 	fmt.Printf("Configuration file: %s\n\n", configPath)
 
 	settings := cfg.List()
@@ -117,6 +276,16 @@ func runConfigList(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// printJSON writes a value to stdout as indented JSON
+func printJSON(value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal configuration: %w", err)
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
 func runConfigRemove(cmd *cobra.Command, args []string) error {
 	key := args[0]
 
@@ -132,3 +301,190 @@ func runConfigRemove(cmd *cobra.Command, args []string) error {
 	fmt.Printf("Removed %s (reverted to default)\n", key)
 	return nil
 }
+
+func runConfigSecretList(cmd *cobra.Command, args []string) error {
+	secretFile := cfg.GetString(config.KeySecretFile)
+	if secretFile == "" {
+		return fmt.Errorf("secret file is not configured, set it with 'llm-caller config secret_file <path>'")
+	}
+
+	fmt.Printf("Secret file: %s\n\n", secretFile)
+
+	keys, err := loadApiKeys(secretFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			fmt.Println("(no keys, file does not exist)")
+			return nil
+		}
+		return fmt.Errorf("failed to load secret file: %w", err)
+	}
+	if len(keys) == 0 {
+		fmt.Println("(no keys)")
+		return nil
+	}
+
+	names := make([]string, 0, len(keys))
+	for name := range keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		masked := make([]string, len(keys[name]))
+		for i, key := range keys[name] {
+			masked[i] = utils.MaskSecret(key)
+		}
+		fmt.Printf("%s: %s\n", name, strings.Join(masked, ", "))
+	}
+	return nil
+}
+
+func runConfigSecretSet(cmd *cobra.Command, args []string) error {
+	name, value := args[0], args[1]
+	if name == "" {
+		return fmt.Errorf("key name cannot be empty")
+	}
+
+	secretFile := cfg.GetString(config.KeySecretFile)
+	if secretFile == "" {
+		return fmt.Errorf("secret file is not configured, set it with 'llm-caller config secret_file <path>'")
+	}
+
+	keys, err := loadApiKeys(secretFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("failed to load secret file: %w", err)
+		}
+		keys = make(map[string]apiKeyValues)
+	}
+	keys[name] = apiKeyValues{value}
+
+	if err := saveApiKeys(secretFile, keys); err != nil {
+		return fmt.Errorf("failed to save secret file: %w", err)
+	}
+
+	fmt.Printf("Set %s in %s\n", name, secretFile)
+	return nil
+}
+
+// saveApiKeys writes API keys to a JSON file, creating it with owner-only permissions
+func saveApiKeys(filePath string, keys map[string]apiKeyValues) error {
+	if err := utils.CreateDirWithPlatformPermissions(filepath.Dir(filePath)); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(keys, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Existing files keep their permissions, new files are only readable by the owner
+	return os.WriteFile(filePath, append(data, '\n'), 0600)
+}
+
+func runConfigExport(cmd *cobra.Command, args []string) error {
+	bundle := configBundle{Version: configBundleVersion, Config: cfg.ExportSettings()}
+
+	if exportSecretsFlag {
+		secretFile := cfg.GetString(config.KeySecretFile)
+		if secretFile == "" {
+			return fmt.Errorf("secret file is not configured, set it with 'llm-caller config secret_file <path>'")
+		}
+		keys, err := loadApiKeys(secretFile)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to load secret file: %w", err)
+		}
+		bundle.Secrets = keys
+		fmt.Fprintf(os.Stderr, "Warning: the bundle contains %d API keys, keep it private\n", len(keys))
+	}
+
+	data, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode configuration: %w", err)
+	}
+	data = append(data, '\n')
+
+	if exportOutputFlag == "" || exportOutputFlag == "-" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+
+	// Bundles with secrets are only readable by the owner, like the secret file
+	perm := utils.GetFilePermissions()
+	if exportSecretsFlag {
+		perm = 0600
+	}
+	if err := os.WriteFile(exportOutputFlag, data, perm); err != nil {
+		return fmt.Errorf("failed to write configuration bundle: %w", err)
+	}
+	infof(os.Stderr, "Configuration exported to %s\n", exportOutputFlag)
+	return nil
+}
+
+func runConfigImport(cmd *cobra.Command, args []string) error {
+	var data []byte
+	var err error
+	if args[0] == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(args[0])
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read configuration bundle: %w", err)
+	}
+
+	var bundle configBundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return fmt.Errorf("failed to parse configuration bundle: %w", err)
+	}
+	if bundle.Version != configBundleVersion {
+		return fmt.Errorf("unsupported configuration bundle version %d, expected %d", bundle.Version, configBundleVersion)
+	}
+
+	// Check the whole bundle before changing anything
+	values, err := config.ImportValues(bundle.Config)
+	if err != nil {
+		return fmt.Errorf("invalid configuration bundle: %w", err)
+	}
+	for key, value := range values {
+		if err := validateConfigValue(key, fmt.Sprint(value)); err != nil {
+			return fmt.Errorf("invalid configuration bundle: %s: %w", key, err)
+		}
+	}
+
+	if err := cfg.SetAll(values); err != nil {
+		return fmt.Errorf("failed to import configuration: %w", err)
+	}
+	fmt.Printf("Imported %d settings into %s\n", len(values), cfg.GetConfigFilePath())
+
+	if len(bundle.Secrets) == 0 {
+		if importSecretsFlag {
+			fmt.Println("The bundle contains no API keys")
+		}
+		return nil
+	}
+	if !importSecretsFlag {
+		fmt.Printf("Skipped %d API keys in the bundle, use --include-secrets to import them\n", len(bundle.Secrets))
+		return nil
+	}
+
+	// The secret file may have just been set by the bundle
+	secretFile := cfg.GetString(config.KeySecretFile)
+	if secretFile == "" {
+		return fmt.Errorf("secret file is not configured, set it with 'llm-caller config secret_file <path>'")
+	}
+	keys, err := loadApiKeys(secretFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("failed to load secret file: %w", err)
+		}
+		keys = make(map[string]apiKeyValues)
+	}
+	for name, values := range bundle.Secrets {
+		keys[name] = values
+	}
+	if err := saveApiKeys(secretFile, keys); err != nil {
+		return fmt.Errorf("failed to save secret file: %w", err)
+	}
+	fmt.Printf("Imported %d API keys into %s\n", len(bundle.Secrets), secretFile)
+	return nil
+}
@@ -1,8 +1,14 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/nodewee/llm-caller/pkg/config"
 	"github.com/nodewee/llm-caller/pkg/templates"
@@ -22,16 +28,80 @@ This command verifies:
 - API keys availability (from file and environment variables)
 - Template file integrity
 
-It will identify issues and provide specific recommendations for fixing them.`,
+It will identify issues and provide specific recommendations for fixing them.
+
+With --fix, missing files and directories are created and the checks are run again:
+- The configuration file
+- The user and default template directories
+- An empty ({}) secret file with owner-only permissions
+
+With --template, a single template is checked end-to-end instead, without calling the API:
+- The template structure (the same checks as 'template validate')
+- An API key can be resolved for its provider (and the signing secret, if any)
+- Every {{variable}} is documented in "variables"
+- The host of the request URL resolves via DNS
+
+Examples:
+  llm-caller doctor
+  llm-caller doctor --fix
+  llm-caller doctor --template deepseek-chat`,
 	RunE: runDoctor,
 }
 
+// Doctor command flags
+var (
+	doctorFixFlag      bool
+	doctorTemplateFlag string
+)
+
+// dnsLookupTimeout bounds the DNS lookup of a template's host
+const dnsLookupTimeout = 5 * time.Second
+
+func init() {
+	doctorCmd.Flags().BoolVar(&doctorFixFlag, "fix", false, "Create missing config file, template directories and secret file, then check again")
+	doctorCmd.Flags().StringVar(&doctorTemplateFlag, "template", "", "Check a single template: structure, API key, documented variables and DNS of its host")
+}
+
 // runDoctor performs environment and configuration checks
 func runDoctor(cmd *cobra.Command, args []string) error {
+	if doctorTemplateFlag != "" {
+		if doctorFixFlag {
+			return fmt.Errorf("--fix cannot be combined with --template")
+		}
+		return runDoctorTemplate(doctorTemplateFlag)
+	}
+
 	fmt.Println("🔍 LLM Caller Environment Check")
 	fmt.Println("================================")
 	fmt.Println()
 
+	issues, totalTemplates := checkEnvironment()
+
+	if doctorFixFlag {
+		fmt.Println()
+		fmt.Println("Fixing:")
+		actions, err := fixEnvironment()
+		for _, action := range actions {
+			fmt.Printf("🔧 %s\n", action)
+		}
+		if err != nil {
+			return fmt.Errorf("doctor --fix failed: %w", err)
+		}
+		if len(actions) == 0 {
+			fmt.Println("ℹ️  Nothing to fix")
+		}
+
+		fmt.Println()
+		fmt.Println("Re-checking:")
+		issues, totalTemplates = checkEnvironment()
+	}
+
+	printDoctorSummary(issues, totalTemplates)
+	return nil
+}
+
+// checkEnvironment prints the result of each check and returns the issues found and the number of templates
+func checkEnvironment() ([]string, int) {
 	var issues []string
 
 	// Check config file
@@ -116,6 +186,11 @@ func runDoctor(cmd *cobra.Command, args []string) error {
 		fmt.Printf("✅ Downloaded templates: %d found\n", len(defaultTemplates))
 	}
 
+	return issues, totalTemplates
+}
+
+// printDoctorSummary prints the issues found with recommendations, or a quick start if there are none
+func printDoctorSummary(issues []string, totalTemplates int) {
 	// Summary
 	fmt.Println()
 	fmt.Println("Summary:")
@@ -138,7 +213,216 @@ func runDoctor(cmd *cobra.Command, args []string) error {
 		fmt.Println("  - Create API keys file with: {\"api_key\": \"sk-xxx\"}")
 		fmt.Println("  - Download templates with: llm-caller template download <url>")
 		fmt.Println("  - Remember: API keys are optional")
+		if !doctorFixFlag {
+			fmt.Println("  - Run 'llm-caller doctor --fix' to create missing files and directories")
+		}
 	}
+}
+
+// fixEnvironment creates the missing config file, template directories and secret file
+// It returns a description of each action taken
+func fixEnvironment() ([]string, error) {
+	var actions []string
+
+	configPath := cfg.GetConfigFilePath()
+	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		if err := cfg.Save(); err != nil {
+			return actions, fmt.Errorf("failed to create config file: %w", err)
+		}
+		actions = append(actions, fmt.Sprintf("Created config file: %s", configPath))
+	}
+
+	templateDirs := []string{cfg.GetString(config.KeyTemplateDir)}
+	if defaultTemplateDir, err := config.GetDefaultTemplateDir(); err == nil {
+		templateDirs = append(templateDirs, defaultTemplateDir)
+	}
+	for _, dir := range templateDirs {
+		if dir == "" {
+			continue
+		}
+		if _, err := os.Stat(dir); !os.IsNotExist(err) {
+			continue
+		}
+		if err := utils.CreateDirWithPlatformPermissions(dir); err != nil {
+			return actions, fmt.Errorf("failed to create template directory: %w", err)
+		}
+		actions = append(actions, fmt.Sprintf("Created template directory: %s", dir))
+	}
+
+	secretFile := cfg.GetString(config.KeySecretFile)
+	if secretFile != "" {
+		if _, err := os.Stat(secretFile); os.IsNotExist(err) {
+			if err := saveApiKeys(secretFile, map[string]apiKeyValues{}); err != nil {
+				return actions, fmt.Errorf("failed to create secret file: %w", err)
+			}
+			actions = append(actions, fmt.Sprintf("Created empty secret file: %s", secretFile))
+		}
+	}
+
+	return actions, nil
+}
+
+// doctorIssue is a problem found by a template check together with how to fix it
+type doctorIssue struct {
+	problem string
+	fix     string
+}
 
+// runDoctorTemplate checks a single template without calling the API
+func runDoctorTemplate(templateName string) error {
+	fmt.Printf("🔍 Template Check: %s\n", templateName)
+	fmt.Println("================================")
+	fmt.Println()
+
+	var issues []doctorIssue
+
+	// Structure, loading also validates the template
+	if err := checkTemplateExists(cfg, templateName); err != nil {
+		fmt.Printf("❌ Template file: not found\n")
+		issues = append(issues, doctorIssue{err.Error(), "Run 'llm-caller template list' to see available templates, or download it with 'llm-caller template download <url>'"})
+		printDoctorTemplateSummary(templateName, issues)
+		return nil
+	}
+	template, path, err := templates.LoadTemplateWithPath(cfg, templateName)
+	if err != nil {
+		fmt.Printf("❌ Structure: invalid\n")
+		issues = append(issues, doctorIssue{err.Error(), "Correct the template file, 'llm-caller template validate " + templateName + "' shows the details"})
+		printDoctorTemplateSummary(templateName, issues)
+		return nil
+	}
+	fmt.Printf("✅ Template file: %s\n", path)
+	fmt.Printf("✅ Structure: valid\n")
+
+	issues = append(issues, checkTemplateAPIKey(template)...)
+	issues = append(issues, checkTemplateVariables(template)...)
+	issues = append(issues, checkTemplateHost(template)...)
+
+	printDoctorTemplateSummary(templateName, issues)
 	return nil
 }
+
+// checkTemplateAPIKey checks that the API key and signing secret the template needs can be resolved
+func checkTemplateAPIKey(template *templates.Template) []doctorIssue {
+	var issues []doctorIssue
+
+	needsKey := template.Auth != nil
+	for _, name := range template.Placeholders() {
+		if name == "api_key" {
+			needsKey = true
+		}
+	}
+
+	apiKey, err := getAPIKey("", "", "", cfg, template)
+	switch {
+	case err != nil:
+		fmt.Printf("❌ API key: %v\n", err)
+		issues = append(issues, doctorIssue{fmt.Sprintf("API key cannot be resolved: %v", err), "Check the secret file entry, e.g. with 'llm-caller config secret ls'"})
+	case apiKey != "":
+		fmt.Printf("✅ API key: found\n")
+	case needsKey:
+		fmt.Printf("❌ API key: not found\n")
+		entry, envName := "api_key", apiKeyEnvNames(template)[0]
+		if template.Provider != "" {
+			entry = template.Provider + "_api_key"
+		}
+		issues = append(issues, doctorIssue{"The template uses an API key but none was found",
+			fmt.Sprintf("Run 'llm-caller config secret set %s <key>' or set the %s environment variable", entry, envName)})
+	default:
+		fmt.Printf("ℹ️  API key: not used by the template\n")
+	}
+
+	if template.Signing != nil {
+		if _, err := getSigningSecret(cfg, template); err != nil {
+			fmt.Printf("❌ Signing secret: %v\n", err)
+			issues = append(issues, doctorIssue{err.Error(), fmt.Sprintf("Run 'llm-caller config secret set %s <secret>'", template.Signing.SecretKeyRef)})
+		} else {
+			fmt.Printf("✅ Signing secret: found\n")
+		}
+	}
+	return issues
+}
+
+// checkTemplateVariables checks that every placeholder is documented and every documented variable is used
+func checkTemplateVariables(template *templates.Template) []doctorIssue {
+	var issues []doctorIssue
+
+	used := make(map[string]bool)
+	var undocumented []string
+	for _, name := range template.Placeholders() {
+		if name == "api_key" {
+			continue
+		}
+		used[name] = true
+		if _, ok := template.Variables[name]; !ok {
+			undocumented = append(undocumented, name)
+		}
+	}
+	var unused []string
+	for name := range template.Variables {
+		if !used[name] {
+			unused = append(unused, name)
+		}
+	}
+	sort.Strings(unused)
+
+	if len(undocumented) == 0 && len(unused) == 0 {
+		fmt.Printf("✅ Variables: %d documented\n", len(used))
+		return nil
+	}
+	if len(undocumented) > 0 {
+		fmt.Printf("⚠️  Variables: not documented: %s\n", strings.Join(undocumented, ", "))
+		issues = append(issues, doctorIssue{"Variables not documented: " + strings.Join(undocumented, ", "),
+			fmt.Sprintf("Describe them in \"variables\", e.g. \"variables\": {\"%s\": {\"description\": \"...\"}}", undocumented[0])})
+	}
+	if len(unused) > 0 {
+		fmt.Printf("⚠️  Variables: documented but not used: %s\n", strings.Join(unused, ", "))
+		issues = append(issues, doctorIssue{"Variables documented but not used in the request: " + strings.Join(unused, ", "),
+			"Remove them from \"variables\" or add the {{placeholders}} to the request"})
+	}
+	return issues
+}
+
+// checkTemplateHost checks that the host of the request URL resolves via DNS
+func checkTemplateHost(template *templates.Template) []doctorIssue {
+	// The configured base URL of the provider may replace the template's
+	requestURL := template.Clone().ApplyProviderDefaults(cfg.GetProviderDefaults(template.Provider)).Request.URL
+
+	parsed, err := url.Parse(requestURL)
+	if err != nil || parsed.Hostname() == "" {
+		if strings.Contains(requestURL, "{{") {
+			fmt.Printf("ℹ️  Host: not checked, the URL depends on variables\n")
+			return nil
+		}
+		fmt.Printf("❌ Host: cannot parse URL %s\n", requestURL)
+		return []doctorIssue{{"Request URL has no host: " + requestURL, "Set request.url to an absolute URL, or the provider's base URL with 'llm-caller config provider.<name>.base_url <url>'"}}
+	}
+	host := parsed.Hostname()
+	if strings.Contains(host, "{{") {
+		fmt.Printf("ℹ️  Host: not checked, the host depends on variables\n")
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dnsLookupTimeout)
+	defer cancel()
+	if _, err := net.DefaultResolver.LookupHost(ctx, host); err != nil {
+		fmt.Printf("❌ Host: %s (DNS lookup failed)\n", host)
+		return []doctorIssue{{fmt.Sprintf("Host %s does not resolve: %v", host, err), "Check request.url for typos and your network or DNS settings"}}
+	}
+	fmt.Printf("✅ Host: %s (resolves)\n", host)
+	return nil
+}
+
+// printDoctorTemplateSummary prints the issues found by the template checks with their fixes
+func printDoctorTemplateSummary(templateName string, issues []doctorIssue) {
+	fmt.Println()
+	fmt.Println("Summary:")
+	if len(issues) == 0 {
+		fmt.Printf("🎉 Template '%s' passed all checks.\n", templateName)
+		return
+	}
+	fmt.Printf("⚠️  Found %d issues:\n", len(issues))
+	for i, issue := range issues {
+		fmt.Printf("  %d. %s\n", i+1, issue.problem)
+		fmt.Printf("     Fix: %s\n", issue.fix)
+	}
+}
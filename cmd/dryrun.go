@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/nodewee/llm-caller/pkg/llm"
+	"github.com/nodewee/llm-caller/pkg/templates"
+	"github.com/nodewee/llm-caller/pkg/utils"
+)
+
+// dryRunCall replaces the variables and prints the resolved request instead of sending it
+// API keys and signing secrets are redacted from the printed request
+func dryRunCall(call *preparedCall) error {
+	template := call.template
+	if _, err := template.ReplaceVariables(call.vars); err != nil {
+		return withStage(stageVariables, err)
+	}
+	if countTokensFlag {
+		printTokenEstimate("", template)
+	}
+	if showCurlFlag || curlSecretsFlag {
+		printCurlCommand(call)
+	}
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "%s %s\n", template.Request.Method, template.Request.URL)
+	names := make([]string, 0, len(template.Request.Headers))
+	for name := range template.Request.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&builder, "%s: %s\n", name, template.Request.Headers[name])
+	}
+	if template.Request.Body != nil {
+		body, err := json.MarshalIndent(template.Request.Body, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal request body: %w", err)
+		}
+		fmt.Fprintf(&builder, "\n%s\n", body)
+	}
+
+	fmt.Print(utils.Redact(builder.String(), resolvedSecrets...))
+	return nil
+}
+
+// printTokenEstimate prints a heuristic token count of the resolved request body to stderr
+// With a pricing block the estimated cost of the prompt is printed as well
+func printTokenEstimate(label string, template *templates.Template) {
+	if label != "" {
+		label += ": "
+	}
+	tokens := llm.EstimateTokens(template.Request.Body)
+	fmt.Fprintf(os.Stderr, "%sEstimated prompt tokens: ~%d (heuristic, text of the request body)\n", label, tokens)
+
+	if template.Pricing != nil {
+		currency := template.Pricing.Currency
+		if currency == "" {
+			currency = "USD"
+		}
+		fmt.Fprintf(os.Stderr, "%sEstimated prompt cost: %.6f %s\n", label, template.Pricing.EstimateCost(tokens, 0), currency)
+	}
+}
+
+// printCurlCommand prints a curl command sending the call's resolved request to stderr
+// Secrets are redacted unless --show-curl-secrets is set; a request curl cannot express only produces a warning
+func printCurlCommand(call *preparedCall) {
+	client, err := llm.NewGenericClient(call.apiKey, call.clientOpts)
+	if err == nil {
+		var command string
+		command, err = client.CurlCommand(call.template, curlSecretsFlag)
+		if err == nil {
+			fmt.Fprintln(os.Stderr, command)
+			return
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Warning: cannot show the curl command: %v\n", err)
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/nodewee/llm-caller/pkg/templates"
+	"github.com/nodewee/llm-caller/pkg/utils"
+)
+
+// defaultOutputPattern names the files written with --output-dir
+const defaultOutputPattern = "file-{i}{ext}"
+
+// fileExtensions maps detected content types of response files to the extension used for {ext}
+var fileExtensions = map[string]string{
+	"image/png":       ".png",
+	"image/jpeg":      ".jpg",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"image/bmp":       ".bmp",
+	"audio/mpeg":      ".mp3",
+	"audio/wave":      ".wav",
+	"audio/ogg":       ".ogg",
+	"video/mp4":       ".mp4",
+	"video/webm":      ".webm",
+	"application/pdf": ".pdf",
+}
+
+// checkOutputDir rejects --output-dir for templates that do not extract files
+func checkOutputDir(template *templates.Template) error {
+	if outputDirFlag != "" && template.Response.Mode != templates.ResponseModeFiles {
+		return fmt.Errorf("--output-dir requires a template with response.mode %s", templates.ResponseModeFiles)
+	}
+	return nil
+}
+
+// writeResponseFiles decodes the JSON array of base64 files extracted by the files response mode
+// and writes each to its own file in dir, printing the paths written
+func writeResponseFiles(content, dir, pattern string) error {
+	var encoded []string
+	if err := json.Unmarshal([]byte(content), &encoded); err != nil {
+		return fmt.Errorf("response files are not a JSON array of strings: %w", err)
+	}
+
+	// Decode everything first so an invalid file leaves no partial set behind
+	files := make([][]byte, len(encoded))
+	for i, value := range encoded {
+		data, err := decodeBase64File(value)
+		if err != nil {
+			return fmt.Errorf("file %d is not valid base64: %w", i+1, err)
+		}
+		files[i] = data
+	}
+
+	if err := utils.CreateDirWithPlatformPermissions(dir); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	for i, data := range files {
+		path := filepath.Join(dir, outputFileName(pattern, i+1, data))
+		if err := writeOutputFile(path, data, false); err != nil {
+			return fmt.Errorf("failed to write output file: %w", err)
+		}
+		fmt.Println(path)
+	}
+	infof(os.Stderr, "Saved %d files to %s\n", len(files), dir)
+	return nil
+}
+
+// decodeBase64File decodes a base64 file, which may be a data URL (data:image/png;base64,...)
+func decodeBase64File(value string) ([]byte, error) {
+	if strings.HasPrefix(value, "data:") {
+		if _, data, ok := strings.Cut(value, ";base64,"); ok {
+			value = data
+		}
+	}
+	value = strings.TrimSpace(value)
+
+	if data, err := base64.StdEncoding.DecodeString(value); err == nil {
+		return data, nil
+	}
+	return base64.RawStdEncoding.DecodeString(value)
+}
+
+// outputFileName expands {i} (the 1-based position of the file) and {ext} (an extension detected from the content) in pattern
+func outputFileName(pattern string, index int, data []byte) string {
+	ext := ".bin"
+	contentType, _, _ := strings.Cut(http.DetectContentType(data), ";")
+	if known, ok := fileExtensions[contentType]; ok {
+		ext = known
+	} else if contentType == "text/plain" {
+		ext = ".txt"
+	}
+
+	name := strings.ReplaceAll(pattern, "{i}", strconv.Itoa(index))
+	return strings.ReplaceAll(name, "{ext}", ext)
+}
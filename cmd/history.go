@@ -0,0 +1,341 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nodewee/llm-caller/pkg/config"
+	"github.com/nodewee/llm-caller/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+// historyFile is the file in the user config directory that call history is appended to
+const historyFile = "history.jsonl"
+
+// redactedVarValue replaces inline --var values in the history, which may hold tokens or whole documents
+const redactedVarValue = utils.RedactedPlaceholder
+
+// historyEntry is one line of the call history
+// Args are the command line arguments as given, so file variables are recorded by path, not content
+type historyEntry struct {
+	Timestamp string   `json:"timestamp"`
+	Dir       string   `json:"dir"`
+	Template  string   `json:"template,omitempty"`
+	Args      []string `json:"args"`
+	Success   bool     `json:"success"`
+}
+
+// History command
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List and re-run past calls",
+	Long: `List and re-run calls recorded with 'call --save-history' or the save_history config.
+
+Each entry holds the time, working directory, template and command line arguments
+of a call. File variables keep their path and are read again when the call is
+re-run. Inline --var values are recorded as [REDACTED], and --api-key values are
+never recorded. History is stored in ~/.llm-caller/history.jsonl.
+
+Examples:
+  llm-caller config save_history true
+  llm-caller history
+  llm-caller history --limit 50
+  llm-caller history run 12`,
+	Args: cobra.NoArgs,
+	RunE: runHistoryList,
+}
+
+var historyRunCmd = &cobra.Command{
+	Use:   "run <index>",
+	Short: "Re-run a call from the history",
+	Long: `Re-run the call with the given index from 'llm-caller history'.
+
+The call runs again in the directory it was made from, with the same arguments.
+Variables read from stdin ('-') read stdin again. Inline --var values are not
+recorded, give them again with --var.
+
+Examples:
+  llm-caller history run 12
+  llm-caller history run 12 --var "prompt:Hello"`,
+	Args: cobra.ExactArgs(1),
+	RunE: runHistoryRun,
+}
+
+// History command flags
+var (
+	historyLimitFlag   int
+	historyRunVarFlags []string
+)
+
+func init() {
+	historyCmd.Flags().IntVar(&historyLimitFlag, "limit", 20, "Number of most recent calls to list, 0 for all")
+	historyRunCmd.Flags().StringArrayVar(&historyRunVarFlags, "var", []string{}, "Variable in 'name[:type]:value' format for a call recorded with [REDACTED] inline values")
+	historyCmd.AddCommand(historyRunCmd)
+}
+
+// isSaveHistory reports whether the call is recorded, --save-history takes precedence over 'config save_history'
+func isSaveHistory(cmd *cobra.Command) bool {
+	if cmd.Flags().Changed("save-history") {
+		return saveHistoryFlag
+	}
+	return cfg.GetBool(config.KeySaveHistory)
+}
+
+// historyFilePath returns the path of the call history file
+func historyFilePath() (string, error) {
+	configDir, err := utils.GetUserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user config directory: %w", err)
+	}
+	return filepath.Join(configDir, historyFile), nil
+}
+
+// saveHistory appends the current command line to the call history, without --api-key and inline --var values
+func saveHistory(templateName string, success bool) error {
+	dir, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	entry := historyEntry{
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Dir:       dir,
+		Template:  templateName,
+		Args:      historyArgs(os.Args[1:]),
+		Success:   success,
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	path, err := historyFilePath()
+	if err != nil {
+		return err
+	}
+	if err := utils.CreateDirWithPlatformPermissions(filepath.Dir(path)); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, utils.GetFilePermissions())
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// historyArgs returns the arguments without --api-key and its value, and with inline --var values redacted
+func historyArgs(args []string) []string {
+	result := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--api-key":
+			i++
+		case strings.HasPrefix(args[i], "--api-key="):
+		case args[i] == "--var" && i+1 < len(args):
+			result = append(result, args[i], redactVar(args[i+1]))
+			i++
+		case strings.HasPrefix(args[i], "--var="):
+			result = append(result, "--var="+redactVar(strings.TrimPrefix(args[i], "--var=")))
+		default:
+			result = append(result, args[i])
+		}
+	}
+	return result
+}
+
+// redactVar replaces the value of a name[:type]:value variable unless it refers to a file or stdin
+func redactVar(variable string) string {
+	parts := strings.SplitN(variable, ":", 3)
+	if len(parts) < 2 {
+		return variable
+	}
+	name, varType, value := parts[0], "text", parts[len(parts)-1]
+	if len(parts) == 3 {
+		varType = parts[1]
+	}
+	switch {
+	case value == "-" || value == redactedVarValue:
+		return variable
+	case varType == "file" || varType == varTypeBase64File || varType == varTypeCSV:
+		return variable
+	case varType == varTypeJSON && strings.HasPrefix(value, jsonFilePrefix):
+		return variable
+	}
+	return name + ":" + varType + ":" + redactedVarValue
+}
+
+// withRedactedVars returns recorded arguments with their redacted --var flags replaced by the given variables
+// It fails if a redacted variable is not given again
+func withRedactedVars(args []string, varFlags []string) ([]string, error) {
+	given := make(map[string]bool)
+	for _, variable := range varFlags {
+		name, _, _ := strings.Cut(variable, ":")
+		given[name] = true
+	}
+
+	result := make([]string, 0, len(args)+2*len(varFlags))
+	var missing []string
+	for i := 0; i < len(args); i++ {
+		variable, isVar := "", false
+		switch {
+		case args[i] == "--var" && i+1 < len(args):
+			variable, isVar = args[i+1], true
+		case strings.HasPrefix(args[i], "--var="):
+			variable, isVar = strings.TrimPrefix(args[i], "--var="), true
+		}
+		if !isVar || !strings.HasSuffix(variable, ":"+redactedVarValue) {
+			result = append(result, args[i])
+			continue
+		}
+
+		if args[i] == "--var" {
+			i++
+		}
+		if name, _, _ := strings.Cut(variable, ":"); !given[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("the values of %s were not recorded, give them with --var", strings.Join(missing, ", "))
+	}
+
+	// Given variables come last, so they also override recorded ones
+	for _, variable := range varFlags {
+		result = append(result, "--var", variable)
+	}
+	return result, nil
+}
+
+// loadHistory reads all entries of the call history, oldest first
+func loadHistory() ([]historyEntry, error) {
+	path, err := historyFilePath()
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open history: %w", err)
+	}
+	defer file.Close()
+
+	var entries []historyEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var entry historyEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("invalid history entry on line %d of %s: %w", line, path, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	return entries, nil
+}
+
+func runHistoryList(cmd *cobra.Command, args []string) error {
+	if historyLimitFlag < 0 {
+		return fmt.Errorf("--limit cannot be negative")
+	}
+	entries, err := loadHistory()
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		fmt.Println("No calls recorded. Use 'call --save-history' or 'llm-caller config save_history true'")
+		return nil
+	}
+
+	start := 0
+	if historyLimitFlag > 0 && len(entries) > historyLimitFlag {
+		start = len(entries) - historyLimitFlag
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "#\tTIME\tSTATUS\tCOMMAND")
+	for i := start; i < len(entries); i++ {
+		entry := entries[i]
+		timestamp := entry.Timestamp
+		if parsed, err := time.Parse(time.RFC3339, entry.Timestamp); err == nil {
+			timestamp = parsed.Local().Format("2006-01-02 15:04:05")
+		}
+		status := "ok"
+		if !entry.Success {
+			status = "failed"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", i+1, timestamp, status, shellCommand(entry.Args))
+	}
+	return w.Flush()
+}
+
+func runHistoryRun(cmd *cobra.Command, args []string) error {
+	index, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid history index '%s', expected a number from 'llm-caller history'", args[0])
+	}
+	entries, err := loadHistory()
+	if err != nil {
+		return err
+	}
+	if index < 1 || index > len(entries) {
+		return fmt.Errorf("history index %d out of range, %d calls recorded", index, len(entries))
+	}
+	entry := entries[index-1]
+	callArgs, err := withRedactedVars(entry.Args, historyRunVarFlags)
+	if err != nil {
+		return fmt.Errorf("cannot re-run call %d: %w", index, err)
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate the llm-caller executable: %w", err)
+	}
+	infof(os.Stderr, "Running: %s\n", shellCommand(callArgs))
+
+	child := exec.Command(executable, callArgs...)
+	child.Dir = entry.Dir
+	child.Stdin = os.Stdin
+	child.Stdout = os.Stdout
+	child.Stderr = os.Stderr
+	if err := child.Run(); err != nil {
+		// The call has reported its own error, only the exit status is passed on
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			cmd.SilenceErrors = true
+			return errReported
+		}
+		return fmt.Errorf("failed to run the call: %w", err)
+	}
+	return nil
+}
+
+// shellCommand formats arguments as an llm-caller command line that can be pasted into a POSIX shell
+func shellCommand(args []string) string {
+	quoted := make([]string, 0, len(args)+1)
+	quoted = append(quoted, "llm-caller")
+	for _, arg := range args {
+		quoted = append(quoted, utils.ShellQuote(arg))
+	}
+	return strings.Join(quoted, " ")
+}
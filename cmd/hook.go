@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nodewee/llm-caller/pkg/llm"
+	"github.com/nodewee/llm-caller/pkg/templates"
+	"github.com/nodewee/llm-caller/pkg/utils"
+)
+
+// defaultPostHookTimeout bounds how long a post hook may run by default
+const defaultPostHookTimeout = 60 * time.Second
+
+// postHookProvider wraps a provider and passes every result through a post hook command
+type postHookProvider struct {
+	provider llm.Provider
+	command  string
+	timeout  time.Duration
+}
+
+// Call calls the wrapped provider and replaces the content with the hook's output
+// Binary responses are output as their body, so the hook receives and replaces the raw body bytes
+func (p *postHookProvider) Call(template *templates.Template) (*llm.Result, error) {
+	result, err := p.provider.Call(template)
+	if err != nil {
+		return nil, err
+	}
+
+	input := result.Content
+	if template.Response.Type == templates.ResponseTypeBinary {
+		input = string(result.Body)
+	}
+	content, err := runPostHook(p.command, input, p.timeout)
+	if err != nil {
+		return nil, err
+	}
+	result.Content = content
+	if template.Response.Type == templates.ResponseTypeBinary {
+		result.Body = []byte(content)
+	}
+	return result, nil
+}
+
+// runPostHook runs a command through the shell with input on its stdin and returns its stdout
+func runPostHook(command, input string, timeout time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	hookCmd := utils.ShellCommand(ctx, command)
+	hookCmd.Stdin = strings.NewReader(input)
+	hookCmd.Stdout = &stdout
+	hookCmd.Stderr = &stderr
+
+	if err := hookCmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("post hook timed out after %s", timeout)
+		}
+		if detail := strings.TrimSpace(stderr.String()); detail != "" {
+			return "", fmt.Errorf("post hook failed: %w: %s", err, detail)
+		}
+		return "", fmt.Errorf("post hook failed: %w", err)
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/nodewee/llm-caller/pkg/llm"
+)
+
+// Stages of a call reported in structured errors
+const (
+	stageArguments    = "arguments"
+	stageVariables    = "variables"
+	stageTemplateLoad = "template_load"
+	stageSetup        = "setup"
+	stageAPICall      = "api_call"
+	stageExtraction   = "extraction"
+	stageOutput       = "output"
+)
+
+// errReported is returned after an error has already been written, so Execute only sets the exit code
+var errReported = errors.New("error already reported")
+
+// stageError records the stage of a call at which an error occurred
+type stageError struct {
+	stage string
+	err   error
+}
+
+func (e *stageError) Error() string {
+	return e.err.Error()
+}
+
+func (e *stageError) Unwrap() error {
+	return e.err
+}
+
+// withStage tags err with the stage it occurred at, keeping a stage set earlier
+func withStage(stage string, err error) error {
+	var tagged *stageError
+	if err == nil || errors.As(err, &tagged) {
+		return err
+	}
+	return &stageError{stage: stage, err: err}
+}
+
+// jsonError is the structured form of a failed call written with --format json
+type jsonError struct {
+	Error  string `json:"error"`
+	Stage  string `json:"stage,omitempty"`
+	Status int    `json:"status,omitempty"`
+	Body   string `json:"body,omitempty"`
+}
+
+// newJSONError describes err, adding the HTTP status and raw response body when the API responded
+func newJSONError(err error) jsonError {
+	result := jsonError{Error: err.Error()}
+
+	var tagged *stageError
+	if errors.As(err, &tagged) {
+		result.Stage = tagged.stage
+	}
+
+	var responseErr *llm.ResponseError
+	if errors.As(err, &responseErr) {
+		result.Status = responseErr.StatusCode
+		result.Body = string(responseErr.Body)
+		// A successful status means the response could not be used rather than the call failing
+		if result.Stage == stageAPICall && responseErr.StatusCode >= 200 && responseErr.StatusCode <= 299 {
+			result.Stage = stageExtraction
+		}
+	}
+	return result
+}
+
+// writeJSONError writes err as a single-line JSON object
+func writeJSONError(w io.Writer, err error) {
+	data, marshalErr := json.Marshal(newJSONError(err))
+	if marshalErr != nil {
+		fmt.Fprintf(w, "%v\n", err)
+		return
+	}
+	fmt.Fprintf(w, "%s\n", data)
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/nodewee/llm-caller/pkg/llm"
+)
+
+// pingValue is used for variables without a value, so the ping request can be sent
+const pingValue = "ping"
+
+// pingTemplate checks that the template's API is reachable and accepts the API key
+// Without a health_url the template's request is sent with its token limit lowered to 1
+func pingTemplate(call *preparedCall) error {
+	template := call.template
+	if template.HealthURL == "" {
+		for _, field := range maxTokensFields {
+			template.SetBodyParameter(field, 1)
+		}
+	}
+
+	vars := mergeVariables(call.vars)
+	inlineDefaults := template.InlineDefaults()
+	for _, name := range template.Placeholders() {
+		_, hasValue := vars[name]
+		_, hasDefault := inlineDefaults[name]
+		if !hasValue && !hasDefault {
+			vars[name] = pingValue
+		}
+	}
+	if _, err := template.ReplaceVariables(vars); err != nil {
+		return err
+	}
+
+	client, err := llm.NewGenericClient(call.apiKey, call.clientOpts)
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+
+	start := time.Now()
+	status, err := client.Ping(template)
+	elapsed := time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return fmt.Errorf("%s: unreachable (%v)", call.name, urlErr.Err)
+		}
+		return fmt.Errorf("%s: ping failed: %w", call.name, err)
+	}
+
+	switch {
+	case status >= 200 && status <= 299:
+		fmt.Printf("%s: ok (status %d, %s)\n", call.name, status, elapsed)
+		return nil
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return fmt.Errorf("%s: auth failed (status %d)", call.name, status)
+	default:
+		return fmt.Errorf("%s: reachable but the request failed (status %d)", call.name, status)
+	}
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/nodewee/llm-caller/pkg/llm"
+	"github.com/spf13/cobra"
+)
+
+// Providers command - lists the response formats recognized by auto-detection
+var providersCmd = &cobra.Command{
+	Use:   "providers",
+	Short: "List the response formats recognized by auto-detection",
+	Long: `List the built-in response formats that auto-detection recognizes.
+
+When response.auto_detect is enabled, the formats are checked in the order shown
+and the first path holding a string is used as the content. If the API you call
+returns a different shape, set response.path in the template instead.
+
+Examples:
+  llm-caller providers`,
+	Args: cobra.NoArgs,
+	RunE: runProviders,
+}
+
+func runProviders(cmd *cobra.Command, args []string) error {
+	fmt.Println("Auto-detected response formats (checked in order):")
+	fmt.Println()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "#\tFORMAT\tCONTENT PATH")
+	for i, format := range llm.ResponseFormats {
+		fmt.Fprintf(w, "%d\t%s\t%s\n", i+1, format.Name, format.Path)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	fmt.Println("For other response shapes, set \"response.path\" in the template, e.g. \"data.output[0].text\".")
+	return nil
+}
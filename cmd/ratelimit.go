@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/nodewee/llm-caller/pkg/config"
+	"github.com/nodewee/llm-caller/pkg/ratelimit"
+	"github.com/nodewee/llm-caller/pkg/utils"
+)
+
+// rateLimitFile stores the token buckets of the provider rate limits, shared by all invocations
+const rateLimitFile = "ratelimit.json"
+
+// newRateLimiter returns the limiter for the provider's configured rate limit, or nil if none is set
+func newRateLimiter(provider string) (*ratelimit.Limiter, error) {
+	spec := cfg.GetRateLimit(provider)
+	if spec == "" {
+		return nil, nil
+	}
+
+	key := strings.ToLower(provider)
+	rate, err := ratelimit.ParseRate(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config %s.%s: %w", config.KeyRateLimitPrefix, key, err)
+	}
+
+	configDir, err := utils.GetUserConfigDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user config directory: %w", err)
+	}
+	return ratelimit.New(filepath.Join(configDir, rateLimitFile), key, rate), nil
+}
@@ -1,9 +1,12 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
 
+	"github.com/joho/godotenv"
 	"github.com/nodewee/llm-caller/pkg/config"
 
 	"github.com/spf13/cobra"
@@ -11,6 +14,12 @@ import (
 
 var (
 	cfg *config.Config
+
+	// Global flags
+	envFileFlags []string
+	profileFlag  string
+	configFlag   string
+	quietFlag    bool
 )
 
 // Root command - simplified with clear subcommands
@@ -22,10 +31,13 @@ var rootCmd = &cobra.Command{
 
 Main Commands:
   call       Execute an LLM API call using a template
+  chat       Start an interactive chat session with a template
   template   Manage template files (download, list, show, validate)
   config     Configure application settings
   doctor     Check configuration and environment
-  version    Display detailed version information with commit hash and build time
+  history    List and re-run past calls
+  providers  List the response formats recognized by auto-detection
+  version    Display version, build, Go runtime and platform information
 
 You can also use the --version flag to display detailed version information.
 
@@ -35,31 +47,85 @@ Examples:
   llm-caller config template_dir ~/my-templates
   llm-caller doctor
   llm-caller version
+  llm-caller --env-file ~/.llm-caller/.env call deepseek-chat --var "prompt:Hello"
+  llm-caller --profile work config list
+  llm-caller --config ./ci/llm-caller.yaml call deepseek-chat --var "prompt:Hello"
+  llm-caller --quiet call deepseek-chat --var "prompt:Hello" -o answer.txt
 
 Use "llm-caller <command> --help" for more information about a command.`,
+	PersistentPreRunE: initialize,
 }
 
-// Initialize commands and configuration
+// Initialize commands
 func init() {
-	// Initialize config
-	var err error
-	cfg, err = config.New()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error initializing config: %v\n", err)
-		os.Exit(1)
-	}
+	// Global flags
+	rootCmd.PersistentFlags().StringArrayVar(&envFileFlags, "env-file", []string{}, "Load environment variables from a dotenv file; repeatable, later files override earlier ones")
+	rootCmd.PersistentFlags().StringVar(&profileFlag, "profile", "", "Configuration profile to use (reads ~/.llm-caller/config.<profile>.yaml, overrides "+config.ProfileEnvVar+")")
+	rootCmd.PersistentFlags().BoolVarP(&quietFlag, "quiet", "q", false, "Suppress informational messages such as saved file paths and download progress; results and errors are still printed")
+	rootCmd.PersistentFlags().StringVar(&configFlag, "config", "", "Use this config file instead of ~/.llm-caller (created if missing, overrides --profile)")
 
 	// Add all subcommands
 	rootCmd.AddCommand(callCmd)
+	rootCmd.AddCommand(chatCmd)
 	rootCmd.AddCommand(configCmd)
 	rootCmd.AddCommand(templateCmd)
 	rootCmd.AddCommand(doctorCmd)
+	rootCmd.AddCommand(historyCmd)
+	rootCmd.AddCommand(providersCmd)
+	rootCmd.AddCommand(versionCmd)
+}
+
+// initialize loads env files and the configuration once flags are parsed
+// Env files come first so they can select the profile via LLM_CALLER_PROFILE
+func initialize(cmd *cobra.Command, args []string) error {
+	if err := loadEnvFiles(cmd, args); err != nil {
+		return err
+	}
+
+	var err error
+	if configFlag != "" {
+		cfg, err = config.NewFromFile(configFlag)
+	} else {
+		cfg, err = config.New(profileFlag)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to initialize config: %w", err)
+	}
+	return nil
+}
+
+// loadEnvFiles loads the dotenv files given with --env-file
+// Values override existing environment variables, and later files override earlier ones
+func loadEnvFiles(cmd *cobra.Command, args []string) error {
+	for _, envFile := range envFileFlags {
+		if _, err := os.Stat(envFile); err != nil {
+			return fmt.Errorf("env file %s not found: %w", envFile, err)
+		}
+		if err := godotenv.Overload(envFile); err != nil {
+			return fmt.Errorf("failed to load env file %s: %w", envFile, err)
+		}
+	}
+	return nil
 }
 
 // Execute executes the root command
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
+		if errors.Is(err, errCancelled) {
+			fmt.Fprintln(os.Stderr, "Cancelled")
+			os.Exit(exitCodeCancelled)
+		}
+		if !errors.Is(err, errReported) {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+		}
 		os.Exit(1)
 	}
 }
+
+// infof prints an informational message to w unless --quiet is set
+func infof(w io.Writer, format string, args ...interface{}) {
+	if quietFlag {
+		return
+	}
+	fmt.Fprintf(w, format, args...)
+}
@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/nodewee/llm-caller/pkg/config"
 	"github.com/nodewee/llm-caller/pkg/download"
+	"github.com/nodewee/llm-caller/pkg/llm"
 	"github.com/nodewee/llm-caller/pkg/templates"
 	"github.com/nodewee/llm-caller/pkg/utils"
 	"github.com/spf13/cobra"
@@ -18,9 +20,10 @@ import (
 var templateCmd = &cobra.Command{
 	Use:   "template",
 	Short: "Manage template files",
-	Long: `Manage template files including downloading, listing, viewing, and validating templates.
+	Long: `Manage template files including downloading, creating, listing, viewing, and validating templates.
 
-Templates define how to call LLM services and are stored in JSON format.
+Templates define how to call LLM services and are stored in JSON format (.json),
+or in YAML (.yaml, .yml).
 The system searches templates in user directory first, then downloaded templates.`,
 }
 
@@ -28,8 +31,17 @@ The system searches templates in user directory first, then downloaded templates
 var templateListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List available templates",
-	Long:  `List all available template files from configured directories.`,
-	RunE:  runTemplateList,
+	Long: `List all available template files from configured directories.
+
+With --search or --provider each template is parsed and only matching templates are
+listed, together with their provider and title. --search matches the file name, title,
+description and provider (case-insensitive); --provider keeps templates of that provider.
+
+Examples:
+  llm-caller template list
+  llm-caller template list --search vision
+  llm-caller template list --provider openai --search chat`,
+	RunE: runTemplateList,
 }
 
 var templateDownloadCmd = &cobra.Command{
@@ -44,9 +56,17 @@ Supported URL formats:
      https://raw.githubusercontent.com/owner/repo/branch/filename.json
      https://raw.githubusercontent.com/owner/repo/refs/heads/branch/filename.json
 
+JSON (.json) and YAML (.yaml, .yml) templates keep their extension; files without
+one are saved as .json. The download is parsed like a local template and removed
+if it is not a valid template.
+
+Proxy settings are taken from HTTP_PROXY, HTTPS_PROXY and NO_PROXY by default,
+or from the --proxy flag when given.
+
 Examples:
   llm-caller template download https://github.com/nodewee/llm-calling-templates/blob/main/deepseek-chat.json
-  llm-caller template download https://raw.githubusercontent.com/nodewee/llm-calling-templates/refs/heads/main/ollama-image-class.json`,
+  llm-caller template download https://raw.githubusercontent.com/nodewee/llm-calling-templates/refs/heads/main/ollama-image-class.json
+  llm-caller template download --proxy http://proxy.local:8080 https://github.com/nodewee/llm-calling-templates/blob/main/deepseek-chat.json`,
 	Args: cobra.ExactArgs(1),
 	RunE: runTemplateDownload,
 }
@@ -56,37 +76,208 @@ var templateShowCmd = &cobra.Command{
 	Short: "Display template content",
 	Long: `Display the content of a specified template file.
 
+By default the template is shown as loaded, with includes and defaults applied.
+Use --raw to print the file exactly as it is stored, including fields the
+template format does not model.
+
 Examples:
   llm-caller template show deepseek-chat
-  llm-caller template show deepseek-chat.json`,
+  llm-caller template show deepseek-chat.json
+  llm-caller template show deepseek-chat --raw`,
 	Args: cobra.ExactArgs(1),
 	RunE: runTemplateShow,
 }
 
 var templateValidateCmd = &cobra.Command{
-	Use:   "validate <template-name>",
+	Use:   "validate [<template-name>]",
 	Short: "Validate template structure",
 	Long: `Validate that a template file has correct JSON structure and required fields.
 
+With --all every template in the user and downloaded template directories is
+validated, with --dir every template in the given directory. A pass or fail line
+is printed per file and the command fails if any template is invalid, e.g. in CI.
+
 This checks for:
 - Valid JSON format
 - Required fields (provider, request URL, request body)
 - Proper structure for HTTP requests
 - Response handling configuration
+- That response.path resolves to a string in "sample_response", if the template has one
 
 Examples:
   llm-caller template validate deepseek-chat
-  llm-caller template validate my-template.json`,
-	Args: cobra.ExactArgs(1),
+  llm-caller template validate my-template.json
+  llm-caller template validate --all
+  llm-caller template validate --dir ./templates`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: runTemplateValidate,
 }
 
+// Template download flags
+var downloadProxyFlag string
+
+var templateNewCmd = &cobra.Command{
+	Use:   "new <name>",
+	Short: "Create a skeleton template",
+	Long: `Create a skeleton template file in the user template directory.
+
+The skeleton contains a placeholder provider, request URL, Authorization header,
+a sample body with {{prompt}} and a response path. Use --provider to pre-fill
+the body shape and response path of a known API.
+
+Supported providers: openai, anthropic, ollama
+
+Examples:
+  llm-caller template new my-chat
+  llm-caller template new my-claude --provider anthropic
+  llm-caller template new my-chat --provider openai --force`,
+	Args: cobra.ExactArgs(1),
+	RunE: runTemplateNew,
+}
+
+var templateVarsCmd = &cobra.Command{
+	Use:   "vars <template-name>",
+	Short: "List the variables a template expects",
+	Long: `List the {{name}} placeholders used in a template's request URL, headers and body.
+
+The auto-injected api_key variable is excluded. If the template declares variables
+in its "variables" field, their description and whether they are required are shown.
+
+Examples:
+  llm-caller template vars deepseek-chat`,
+	Args: cobra.ExactArgs(1),
+	RunE: runTemplateVars,
+}
+
+var templateUpdateCmd = &cobra.Command{
+	Use:   "update [<template-name>]",
+	Short: "Re-download templates from their source URL",
+	Long: `Re-download a downloaded template from the URL it was originally downloaded from.
+
+The source URL is recorded next to the template when running 'template download'.
+Use --all to refresh every downloaded template and report which ones changed.
+
+Examples:
+  llm-caller template update deepseek-chat
+  llm-caller template update --all`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runTemplateUpdate,
+}
+
+var templateDiffCmd = &cobra.Command{
+	Use:   "diff <template-name>",
+	Short: "Show upstream changes of a downloaded template",
+	Long: `Fetch the current version of a downloaded template from the URL it was downloaded
+from and print a unified diff against the local file, without modifying it.
+
+Use it to review changes, e.g. to URLs and headers, before running 'template update'.
+Nothing is printed if the template is up to date.
+
+Examples:
+  llm-caller template diff deepseek-chat
+  llm-caller template diff deepseek-chat --proxy http://proxy.local:8080`,
+	Args: cobra.ExactArgs(1),
+	RunE: runTemplateDiff,
+}
+
+var templateRenameCmd = &cobra.Command{
+	Use:   "rename <template-name> <new-name>",
+	Short: "Rename a template",
+	Long: `Rename a template, moving it into the user template directory.
+
+The template is found with the usual search order (user directory, then downloaded
+templates) and must load and validate. The new name gets the extension of the
+template (.json, .yaml or .yml) unless it has one. An existing template is not
+overwritten unless --force is given. The recorded source URL of a downloaded
+template moves with it.
+
+Examples:
+  llm-caller template rename deepseek-chat deepseek
+  llm-caller template rename my-chat my-chat-old --force`,
+	Args: cobra.ExactArgs(2),
+	RunE: runTemplateRename,
+}
+
+var templateCopyCmd = &cobra.Command{
+	Use:   "copy <template-name> <new-name>",
+	Short: "Copy a template",
+	Long: `Copy a template into the user template directory under a new name.
+
+The template is found with the usual search order (user directory, then downloaded
+templates) and must load and validate; the file is copied verbatim. The new name
+gets the extension of the template (.json, .yaml or .yml) unless it has one. An
+existing template is not overwritten unless --force is given.
+
+Examples:
+  llm-caller template copy deepseek-chat my-deepseek
+  llm-caller template copy deepseek-chat my-deepseek.json --force`,
+	Args: cobra.ExactArgs(2),
+	RunE: runTemplateCopy,
+}
+
+// Template rename and copy flags
+var (
+	renameForceFlag bool
+	copyForceFlag   bool
+)
+
+// Template diff flags
+var diffProxyFlag string
+
+// Template update flags
+var (
+	updateAllFlag   bool
+	updateProxyFlag string
+)
+
+// Template list flags
+var (
+	listSearchFlag   string
+	listProviderFlag string
+)
+
+// Template show flags
+var showRawFlag bool
+
+// Template validate flags
+var (
+	validateAllFlag bool
+	validateDirFlag string
+)
+
+// Template new flags
+var (
+	newProviderFlag string
+	newForceFlag    bool
+)
+
 func init() {
+	templateUpdateCmd.Flags().BoolVar(&updateAllFlag, "all", false, "Update all downloaded templates")
+	templateUpdateCmd.Flags().StringVar(&updateProxyFlag, "proxy", "", "Proxy URL (http, https or socks5), overrides HTTP_PROXY/HTTPS_PROXY")
+	templateNewCmd.Flags().StringVar(&newProviderFlag, "provider", "", "Pre-fill the template for a provider: openai, anthropic or ollama")
+	templateNewCmd.Flags().BoolVar(&newForceFlag, "force", false, "Overwrite an existing template file")
+	templateRenameCmd.Flags().BoolVar(&renameForceFlag, "force", false, "Overwrite an existing template file")
+	templateCopyCmd.Flags().BoolVar(&copyForceFlag, "force", false, "Overwrite an existing template file")
+	templateListCmd.Flags().StringVar(&listSearchFlag, "search", "", "Only list templates whose file name, title, description or provider contains this term")
+	templateListCmd.Flags().StringVar(&listProviderFlag, "provider", "", "Only list templates of this provider")
+	templateValidateCmd.Flags().BoolVar(&validateAllFlag, "all", false, "Validate every template in the user and downloaded template directories")
+	templateValidateCmd.Flags().StringVar(&validateDirFlag, "dir", "", "Validate every template in this directory")
+	templateShowCmd.Flags().BoolVar(&showRawFlag, "raw", false, "Print the template file verbatim instead of the parsed template")
+
+	templateDiffCmd.Flags().StringVar(&diffProxyFlag, "proxy", "", "Proxy URL (http, https or socks5), overrides HTTP_PROXY/HTTPS_PROXY")
+	templateDownloadCmd.Flags().StringVar(&downloadProxyFlag, "proxy", "", "Proxy URL (http, https or socks5), overrides HTTP_PROXY/HTTPS_PROXY")
+
 	// Template subcommands
 	templateCmd.AddCommand(templateListCmd)
 	templateCmd.AddCommand(templateDownloadCmd)
 	templateCmd.AddCommand(templateShowCmd)
 	templateCmd.AddCommand(templateValidateCmd)
+	templateCmd.AddCommand(templateNewCmd)
+	templateCmd.AddCommand(templateVarsCmd)
+	templateCmd.AddCommand(templateUpdateCmd)
+	templateCmd.AddCommand(templateDiffCmd)
+	templateCmd.AddCommand(templateRenameCmd)
+	templateCmd.AddCommand(templateCopyCmd)
 }
 
 // Template command handlers
@@ -106,6 +297,7 @@ func runTemplateList(cmd *cobra.Command, args []string) error {
 		if err != nil {
 			return fmt.Errorf("failed to list user templates: %w", err)
 		}
+		userTemplates = filterTemplateList(userTemplateDir, userTemplates)
 
 		fmt.Printf("User templates (%s):\n", userTemplateDir)
 		if len(userTemplates) == 0 {
@@ -124,6 +316,7 @@ func runTemplateList(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("failed to list default templates: %w", err)
 	}
+	defaultTemplates = filterTemplateList(defaultTemplateDir, defaultTemplates)
 
 	fmt.Printf("Downloaded templates (%s):\n", defaultTemplateDir)
 	if len(defaultTemplates) == 0 {
@@ -135,10 +328,65 @@ func runTemplateList(cmd *cobra.Command, args []string) error {
 	}
 	totalCount += len(defaultTemplates)
 
+	// Aliases configured with 'config alias.<name> <template>'
+	if aliases := cfg.GetTemplateAliases(); len(aliases) > 0 && listSearchFlag == "" && listProviderFlag == "" {
+		names := make([]string, 0, len(aliases))
+		for name := range aliases {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		fmt.Println("\nAliases:")
+		for _, name := range names {
+			fmt.Printf("  - %s -> %s\n", name, aliases[name])
+		}
+	}
+
 	fmt.Printf("\nTotal: %d templates found\n", totalCount)
 	return nil
 }
 
+// filterTemplateList applies the --search and --provider filters to the template files of dir
+// Matching templates are described with their provider and title; without filters the names are returned unchanged
+func filterTemplateList(dir string, names []string) []string {
+	if listSearchFlag == "" && listProviderFlag == "" {
+		return names
+	}
+
+	search := strings.ToLower(listSearchFlag)
+	var matches []string
+	for _, name := range names {
+		template, err := templates.LoadTemplate(cfg, filepath.Join(dir, name))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: skipping %s: %v\n", name, err)
+			continue
+		}
+		if listProviderFlag != "" && !strings.EqualFold(template.Provider, listProviderFlag) {
+			continue
+		}
+		if search != "" && !containsFold(search, name, template.Title, template.Description, template.Provider) {
+			continue
+		}
+
+		line := fmt.Sprintf("%s (%s)", name, template.Provider)
+		if template.Title != "" {
+			line += " - " + template.Title
+		}
+		matches = append(matches, line)
+	}
+	return matches
+}
+
+// containsFold reports whether any of the values contains the lower case term, ignoring case
+func containsFold(term string, values ...string) bool {
+	for _, value := range values {
+		if strings.Contains(strings.ToLower(value), term) {
+			return true
+		}
+	}
+	return false
+}
+
 func runTemplateDownload(cmd *cobra.Command, args []string) error {
 	githubURL := args[0]
 
@@ -154,7 +402,11 @@ func runTemplateDownload(cmd *cobra.Command, args []string) error {
 	}
 
 	// Create downloader and download the template
-	downloader := download.NewGitHubDownloader()
+	downloader, err := download.NewGitHubDownloader(downloadProxyFlag)
+	if err != nil {
+		return err
+	}
+	downloader.SetQuiet(quietFlag)
 	filePath, err := downloader.DownloadTemplate(githubURL, defaultTemplateDir)
 	if err != nil {
 		return fmt.Errorf("failed to download template: %w", err)
@@ -167,57 +419,56 @@ func runTemplateDownload(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("downloaded file is not a valid template: %w", err)
 	}
 
-	fmt.Printf("Template successfully downloaded to: %s\n", filePath)
+	// Record the source URL so 'template update' can refresh the template
+	if err := download.SaveSourceURL(filePath, githubURL); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to record source URL: %v\n", err)
+	}
+
+	infof(os.Stdout, "Template successfully downloaded to: %s\n", filePath)
 	return nil
 }
 
 // checkTemplateExists checks if a template file exists before trying to load it
+// Names without an extension match .json, .yaml and .yml files
 func checkTemplateExists(cfg *config.Config, templateName string) error {
-	// Automatically append .json extension if not present
-	if !strings.HasSuffix(templateName, ".json") {
-		templateName = templateName + ".json"
-	}
+	templateName = cfg.ResolveTemplateAlias(templateName)
+	candidates := templates.CandidateFileNames(templateName)
 
 	// Check if it's a direct path (absolute or contains path separators)
 	isDirectPath := filepath.IsAbs(templateName) || strings.ContainsAny(templateName, "/\\")
 
 	if isDirectPath {
-		// Normalize path for cross-platform compatibility
-		templateName = filepath.Clean(filepath.FromSlash(templateName))
-		if _, err := os.Stat(templateName); os.IsNotExist(err) {
-			return fmt.Errorf("template file not found: %s", templateName)
+		for _, candidate := range candidates {
+			// Normalize path for cross-platform compatibility
+			if _, err := os.Stat(filepath.Clean(filepath.FromSlash(candidate))); err == nil {
+				return nil
+			}
 		}
-		return nil
+		return fmt.Errorf("template file not found: %s", filepath.Clean(filepath.FromSlash(candidates[0])))
 	}
 
 	// For template names without path separators, search in directories
-	var exists bool
+	var dirs []string
 
 	// First, try user configured template directory
-	userTemplateDir := cfg.GetString(config.KeyTemplateDir)
-	if userTemplateDir != "" {
-		userTemplatePath := filepath.Join(userTemplateDir, templateName)
-		if _, err := os.Stat(userTemplatePath); err == nil {
-			exists = true
-		}
+	if userTemplateDir := cfg.GetString(config.KeyTemplateDir); userTemplateDir != "" {
+		dirs = append(dirs, userTemplateDir)
 	}
 
 	// Second, try default app config templates directory
-	if !exists {
-		defaultTemplateDir, err := config.GetDefaultTemplateDir()
-		if err == nil {
-			defaultTemplatePath := filepath.Join(defaultTemplateDir, templateName)
-			if _, err := os.Stat(defaultTemplatePath); err == nil {
-				exists = true
-			}
-		}
+	if defaultTemplateDir, err := config.GetDefaultTemplateDir(); err == nil {
+		dirs = append(dirs, defaultTemplateDir)
 	}
 
-	if !exists {
-		return fmt.Errorf("template file not found: %s", templateName)
+	for _, dir := range dirs {
+		for _, candidate := range candidates {
+			if _, err := os.Stat(filepath.Join(dir, candidate)); err == nil {
+				return nil
+			}
+		}
 	}
 
-	return nil
+	return fmt.Errorf("template file not found: %s", candidates[0])
 }
 
 func runTemplateShow(cmd *cobra.Command, args []string) error {
@@ -228,6 +479,16 @@ func runTemplateShow(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	// Print the file content verbatim
+	if showRawFlag {
+		_, data, err := templates.ReadTemplateFile(cfg, templateName)
+		if err != nil {
+			return err
+		}
+		os.Stdout.Write(data)
+		return nil
+	}
+
 	// Load the template
 	template, err := templates.LoadTemplate(cfg, templateName)
 	if err != nil {
@@ -245,6 +506,13 @@ func runTemplateShow(cmd *cobra.Command, args []string) error {
 }
 
 func runTemplateValidate(cmd *cobra.Command, args []string) error {
+	bulk := validateAllFlag || validateDirFlag != ""
+	if bulk == (len(args) == 1) || (validateAllFlag && validateDirFlag != "") {
+		return fmt.Errorf("specify either a template name, --all or --dir")
+	}
+	if bulk {
+		return validateTemplateDirs()
+	}
 	templateName := args[0]
 
 	// First check if the template exists
@@ -252,17 +520,11 @@ func runTemplateValidate(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	// Try to load and validate the template
-	template, err := templates.LoadTemplate(cfg, templateName)
+	template, responseMode, sampleContent, err := loadAndCheckTemplate(templateName)
 	if err != nil {
 		return fmt.Errorf("template validation failed: %w", err)
 	}
 
-	// Additional validation
-	if err := template.Validate(); err != nil {
-		return fmt.Errorf("template validation failed: %w", err)
-	}
-
 	fmt.Printf("✅ Template '%s' is valid\n", templateName)
 	fmt.Printf("Provider: %s\n", template.Provider)
 	fmt.Printf("URL: %s\n", template.Request.URL)
@@ -274,6 +536,531 @@ func runTemplateValidate(cmd *cobra.Command, args []string) error {
 	if template.Description != "" {
 		fmt.Printf("Description: %s\n", template.Description)
 	}
+	if template.Response.IsRaw() {
+		fmt.Printf("Response: %s\n", responseMode)
+	} else if len(template.SampleResponse) > 0 {
+		fmt.Printf("Response: %s (resolves in sample_response)\n", responseMode)
+		fmt.Printf("Extracted value: %s\n", sampleContent)
+	} else {
+		fmt.Printf("Response: %s (not checked, add \"sample_response\" to test it)\n", responseMode)
+	}
+
+	return nil
+}
+
+// loadAndCheckTemplate loads and validates a template and checks its response extraction against sample_response
+// It returns the template, a description of how content is extracted and the value extracted from the sample
+func loadAndCheckTemplate(templateName string) (*templates.Template, string, string, error) {
+	template, err := templates.LoadTemplate(cfg, templateName)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	// Additional validation
+	if err := template.Validate(); err != nil {
+		return nil, "", "", err
+	}
+
+	// Describe how the content is extracted
+	responseMode := fmt.Sprintf("path %s", template.Response.Path)
+	if template.Response.AutoDetectEnabled() {
+		responseMode = "auto-detect"
+		if template.Response.Path != "" {
+			responseMode += fmt.Sprintf(", falling back to path %s", template.Response.Path)
+		}
+	}
+	if len(template.Response.Paths) > 0 {
+		responseMode = fmt.Sprintf("%d named paths", len(template.Response.Paths))
+	}
+	if template.Response.Mode != "" {
+		responseMode = "mode " + template.Response.Mode
+	}
+	if template.Response.Expr != "" {
+		responseMode = "expr " + template.Response.Expr
+	}
+	if template.Response.IsRaw() {
+		responseMode = "whole body as " + template.Response.Type
+	}
+
+	// Check the response extraction against the sample response, if provided
+	var sampleContent string
+	if len(template.SampleResponse) > 0 && !template.Response.IsRaw() {
+		sampleContent, err = llm.CheckResponseContent(template.SampleResponse, template.Response)
+		if err != nil {
+			return nil, "", "", fmt.Errorf("response (%s) does not resolve in sample_response: %w", responseMode, err)
+		}
+	}
+	return template, responseMode, sampleContent, nil
+}
+
+// validateTemplateDirs validates every template file in the directory given with --dir,
+// or with --all in the user and downloaded template directories, printing a line per file
+func validateTemplateDirs() error {
+	dirs := []string{validateDirFlag}
+	if validateAllFlag {
+		dirs = nil
+		if userTemplateDir := cfg.GetString(config.KeyTemplateDir); userTemplateDir != "" {
+			dirs = append(dirs, userTemplateDir)
+		}
+		defaultTemplateDir, err := config.GetDefaultTemplateDir()
+		if err != nil {
+			return fmt.Errorf("failed to get default template directory: %w", err)
+		}
+		if len(dirs) == 0 || filepath.Clean(dirs[0]) != filepath.Clean(defaultTemplateDir) {
+			dirs = append(dirs, defaultTemplateDir)
+		}
+	} else if info, err := os.Stat(validateDirFlag); err != nil || !info.IsDir() {
+		return fmt.Errorf("template directory not found: %s", validateDirFlag)
+	}
+
+	total, failed := 0, 0
+	for _, dir := range dirs {
+		templateFiles, err := templates.ListTemplates(dir)
+		if err != nil {
+			return err
+		}
+		for _, templateFile := range templateFiles {
+			total++
+			templatePath := filepath.Join(dir, templateFile)
+			if _, _, _, err := loadAndCheckTemplate(templatePath); err != nil {
+				failed++
+				fmt.Printf("❌ %s: %v\n", templatePath, err)
+				continue
+			}
+			fmt.Printf("✅ %s\n", templatePath)
+		}
+	}
+
+	if total == 0 {
+		fmt.Printf("No templates found in %s\n", strings.Join(dirs, ", "))
+		return nil
+	}
+	fmt.Printf("\n%d of %d templates valid\n", total-failed, total)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d templates failed validation", failed, total)
+	}
+	return nil
+}
+
+func runTemplateNew(cmd *cobra.Command, args []string) error {
+	name := strings.TrimSuffix(args[0], ".json")
+	if name == "" || strings.ContainsAny(name, "/\\") {
+		return fmt.Errorf("invalid template name '%s', expected a name without path separators", args[0])
+	}
+
+	template, err := newTemplateSkeleton(newProviderFlag)
+	if err != nil {
+		return err
+	}
+
+	templateDir, err := cfg.EnsureTemplateDir()
+	if err != nil {
+		return err
+	}
+
+	filePath := filepath.Join(templateDir, name+".json")
+	if _, err := os.Stat(filePath); err == nil && !newForceFlag {
+		return fmt.Errorf("template already exists: %s (use --force to overwrite)", filePath)
+	}
+
+	jsonData, err := json.MarshalIndent(template, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal template: %w", err)
+	}
+
+	if err := os.WriteFile(filePath, append(jsonData, '\n'), utils.GetFilePermissions()); err != nil {
+		return fmt.Errorf("failed to write template: %w", err)
+	}
+
+	fmt.Printf("Template created: %s\n", filePath)
+	fmt.Printf("Edit the file, then run: llm-caller call %s --var \"prompt:Hello\"\n", name)
+	return nil
+}
+
+// newTemplateSkeleton returns a skeleton template, pre-filled for a known provider
+func newTemplateSkeleton(provider string) (*templates.Template, error) {
+	messages := []interface{}{
+		map[string]interface{}{"role": "user", "content": "{{prompt}}"},
+	}
+
+	switch provider {
+	case "":
+		return &templates.Template{
+			Provider:    "my-provider",
+			Title:       "My Template",
+			Description: "Describe what this template does",
+			Request: templates.RequestConfig{
+				URL:     "https://api.example.com/v1/chat/completions",
+				Method:  "POST",
+				Headers: map[string]string{"Authorization": "Bearer {{api_key}}", "Content-Type": "application/json"},
+				Body:    map[string]interface{}{"model": "my-model", "messages": messages},
+			},
+			Response: templates.ResponseConfig{Path: "choices[0].message.content"},
+		}, nil
+	case "openai":
+		return &templates.Template{
+			Provider:    "openai",
+			Title:       "OpenAI Chat Completions",
+			Description: "Template for calling OpenAI's chat completion API",
+			APIDocument: "https://platform.openai.com/docs/api-reference/chat",
+			Request: templates.RequestConfig{
+				URL:     "https://api.openai.com/v1/chat/completions",
+				Method:  "POST",
+				Headers: map[string]string{"Authorization": "Bearer {{api_key}}", "Content-Type": "application/json"},
+				Body:    map[string]interface{}{"model": "gpt-4o-mini", "messages": messages},
+			},
+			Response: templates.ResponseConfig{Path: "choices[0].message.content"},
+		}, nil
+	case "anthropic":
+		return &templates.Template{
+			Provider:    "anthropic",
+			Title:       "Anthropic Messages",
+			Description: "Template for calling Anthropic's messages API",
+			APIDocument: "https://docs.anthropic.com/en/api/messages",
+			Request: templates.RequestConfig{
+				URL:    "https://api.anthropic.com/v1/messages",
+				Method: "POST",
+				Headers: map[string]string{
+					"x-api-key":         "{{api_key}}",
+					"anthropic-version": "2023-06-01",
+					"Content-Type":      "application/json",
+				},
+				Body: map[string]interface{}{"model": "claude-3-5-haiku-latest", "max_tokens": 1024, "messages": messages},
+			},
+			Response: templates.ResponseConfig{Path: "content[0].text"},
+		}, nil
+	case "ollama":
+		return &templates.Template{
+			Provider:    "ollama",
+			Title:       "Ollama Generate",
+			Description: "Template for calling a local Ollama server (no API key required)",
+			APIDocument: "https://github.com/ollama/ollama/blob/main/docs/api.md",
+			Request: templates.RequestConfig{
+				URL:     "http://localhost:11434/api/generate",
+				Method:  "POST",
+				Headers: map[string]string{"Content-Type": "application/json"},
+				Body:    map[string]interface{}{"model": "llama3.2", "prompt": "{{prompt}}", "stream": false},
+			},
+			Response: templates.ResponseConfig{Path: "response"},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported provider '%s', supported providers: openai, anthropic, ollama", provider)
+	}
+}
+
+func runTemplateVars(cmd *cobra.Command, args []string) error {
+	templateName := args[0]
+
+	// First check if the template exists
+	if err := checkTemplateExists(cfg, templateName); err != nil {
+		return err
+	}
+
+	template, err := templates.LoadTemplate(cfg, templateName)
+	if err != nil {
+		return fmt.Errorf("failed to load template: %w", err)
+	}
+
+	// Placeholders in the request, then declared variables that are not used
+	var names []string
+	used := make(map[string]bool)
+	for _, name := range template.Placeholders() {
+		if name == "api_key" {
+			continue
+		}
+		names = append(names, name)
+		used[name] = true
+	}
+	var unused []string
+	for name := range template.Variables {
+		if !used[name] {
+			unused = append(unused, name)
+		}
+	}
+	sort.Strings(unused)
+
+	if len(names) == 0 && len(unused) == 0 {
+		fmt.Printf("Template '%s' has no variables\n", templateName)
+		return nil
+	}
+
+	fmt.Printf("Variables in template '%s':\n", templateName)
+	inlineDefaults := template.InlineDefaults()
+	for _, name := range names {
+		spec, declared := template.Variables[name]
+		status := "required"
+		if value, ok := template.Defaults[name]; ok {
+			status = fmt.Sprintf("default: %q", value)
+		} else if value, ok := inlineDefaults[name]; ok {
+			status = fmt.Sprintf("inline default: %q", value)
+		} else if declared && !spec.IsRequired() {
+			status = "optional"
+		}
+		line := fmt.Sprintf("  - %s (%s)", name, status)
+		if spec.Description != "" {
+			line += ": " + spec.Description
+		}
+		fmt.Println(line)
+	}
+	for _, name := range unused {
+		fmt.Printf("  - %s (declared but not used in the request)\n", name)
+	}
+
+	fmt.Printf("\nUsage: llm-caller call %s", templates.TrimTemplateExt(templateName))
+	for _, name := range names {
+		fmt.Printf(" --var \"%s:...\"", name)
+	}
+	fmt.Println()
+	return nil
+}
+
+func runTemplateUpdate(cmd *cobra.Command, args []string) error {
+	if updateAllFlag == (len(args) == 1) {
+		return fmt.Errorf("specify either a template name or --all")
+	}
+
+	defaultTemplateDir, err := config.GetDefaultTemplateDir()
+	if err != nil {
+		return fmt.Errorf("failed to get default template directory: %w", err)
+	}
+
+	downloader, err := download.NewGitHubDownloader(updateProxyFlag)
+	if err != nil {
+		return err
+	}
+	downloader.SetQuiet(quietFlag)
+
+	if !updateAllFlag {
+		templatePath, err := downloadedTemplatePath(defaultTemplateDir, args[0])
+		if err != nil {
+			return err
+		}
+
+		changed, err := downloader.UpdateTemplate(templatePath)
+		if err != nil {
+			return fmt.Errorf("failed to update template: %w", err)
+		}
+		if changed {
+			fmt.Printf("Template updated: %s\n", templatePath)
+		} else {
+			fmt.Printf("Template already up to date: %s\n", templatePath)
+		}
+		return nil
+	}
+
+	templateFiles, err := templates.ListTemplates(defaultTemplateDir)
+	if err != nil {
+		return fmt.Errorf("failed to list downloaded templates: %w", err)
+	}
+
+	if len(templateFiles) == 0 {
+		fmt.Printf("No downloaded templates found in %s\n", defaultTemplateDir)
+		return nil
+	}
+
+	var changed, unchanged, skipped, failed []string
+	for _, templateFile := range templateFiles {
+		templatePath := filepath.Join(defaultTemplateDir, templateFile)
+		if _, err := download.LoadSourceURL(templatePath); err != nil {
+			skipped = append(skipped, templateFile)
+			continue
+		}
+
+		infof(os.Stdout, "Updating %s\n", templateFile)
+		updated, err := downloader.UpdateTemplate(templatePath)
+		switch {
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "Failed to update %s: %v\n", templateFile, err)
+			failed = append(failed, templateFile)
+		case updated:
+			changed = append(changed, templateFile)
+		default:
+			unchanged = append(unchanged, templateFile)
+		}
+	}
+
+	fmt.Println()
+	printTemplateGroup("Changed", changed)
+	printTemplateGroup("Unchanged", unchanged)
+	printTemplateGroup("Skipped (no source URL recorded)", skipped)
+	printTemplateGroup("Failed", failed)
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d templates failed to update", len(failed), len(templateFiles))
+	}
+	return nil
+}
+
+// printTemplateGroup prints a labelled list of template names, omitting empty groups
+func printTemplateGroup(label string, names []string) {
+	if len(names) == 0 {
+		return
+	}
+	fmt.Printf("%s (%d):\n", label, len(names))
+	for _, name := range names {
+		fmt.Printf("  - %s\n", name)
+	}
+}
 
+// downloadedTemplatePath resolves a template name to its file in the downloaded templates directory
+// Paths containing a separator are used as given. Names without an extension match .json, .yaml and .yml files
+func downloadedTemplatePath(defaultTemplateDir, templateName string) (string, error) {
+	isDirectPath := filepath.IsAbs(templateName) || strings.ContainsAny(templateName, "/\\")
+
+	var attemptedPaths []string
+	for _, candidate := range templates.CandidateFileNames(templateName) {
+		templatePath := candidate
+		if !isDirectPath {
+			templatePath = filepath.Join(defaultTemplateDir, candidate)
+		}
+		if _, err := os.Stat(templatePath); err == nil {
+			return templatePath, nil
+		}
+		attemptedPaths = append(attemptedPaths, templatePath)
+	}
+	return "", fmt.Errorf("downloaded template not found, tried paths: %s", strings.Join(attemptedPaths, ", "))
+}
+
+func runTemplateDiff(cmd *cobra.Command, args []string) error {
+	defaultTemplateDir, err := config.GetDefaultTemplateDir()
+	if err != nil {
+		return fmt.Errorf("failed to get default template directory: %w", err)
+	}
+	templatePath, err := downloadedTemplatePath(defaultTemplateDir, args[0])
+	if err != nil {
+		return err
+	}
+
+	localData, err := os.ReadFile(templatePath)
+	if err != nil {
+		return fmt.Errorf("failed to read template: %w", err)
+	}
+
+	downloader, err := download.NewGitHubDownloader(diffProxyFlag)
+	if err != nil {
+		return err
+	}
+	// Progress messages would mix with the diff on stdout
+	downloader.SetQuiet(true)
+	upstreamData, sourceURL, err := downloader.FetchUpstream(templatePath)
+	if err != nil {
+		return fmt.Errorf("failed to fetch upstream template: %w", err)
+	}
+
+	fmt.Print(utils.UnifiedDiff(string(localData), string(upstreamData), templatePath, sourceURL))
+	return nil
+}
+
+func runTemplateRename(cmd *cobra.Command, args []string) error {
+	sourcePath, destPath, err := copyTemplateFile(args[0], args[1], renameForceFlag)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(sourcePath); err != nil {
+		return fmt.Errorf("template copied to %s, but failed to remove %s: %w", destPath, sourcePath, err)
+	}
+	// Keep the source URL of a downloaded template, so that it can still be updated
+	if sourceURL, err := download.LoadSourceURL(sourcePath); err == nil {
+		if err := download.SaveSourceURL(destPath, sourceURL); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to record source URL: %v\n", err)
+		}
+		os.Remove(download.SourceURLPath(sourcePath))
+	}
+
+	fmt.Printf("Template renamed: %s -> %s\n", sourcePath, destPath)
 	return nil
 }
+
+func runTemplateCopy(cmd *cobra.Command, args []string) error {
+	sourcePath, destPath, err := copyTemplateFile(args[0], args[1], copyForceFlag)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Template copied: %s -> %s\n", sourcePath, destPath)
+	return nil
+}
+
+// copyTemplateFile copies a valid template verbatim to a new name in the user template directory
+// It returns the source and destination paths. Nothing is written if the copy does not load from
+// its new location, e.g. because of a relative include or body_file
+func copyTemplateFile(sourceName, destName string, force bool) (string, string, error) {
+	if err := checkTemplateExists(cfg, sourceName); err != nil {
+		return "", "", err
+	}
+	template, sourcePath, err := templates.LoadTemplateWithPath(cfg, sourceName)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to load template: %w", err)
+	}
+	if err := template.Validate(); err != nil {
+		return "", "", fmt.Errorf("template %s is not valid: %w", sourceName, err)
+	}
+
+	destPath, err := templateDestPath(destName, filepath.Ext(sourcePath))
+	if err != nil {
+		return "", "", err
+	}
+	if absPath, err := filepath.Abs(destPath); err == nil && absPath == sourcePath {
+		return "", "", fmt.Errorf("source and destination are the same file: %s", sourcePath)
+	}
+	if _, err := os.Stat(destPath); err == nil && !force {
+		return "", "", fmt.Errorf("template already exists: %s (use --force to overwrite)", destPath)
+	}
+
+	data, err := os.ReadFile(sourcePath)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read template: %w", err)
+	}
+
+	// Write and check a temporary file next to the destination, so a failed copy leaves no trace
+	tempFile, err := os.CreateTemp(filepath.Dir(destPath), ".copy-*"+filepath.Ext(destPath))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to write template: %w", err)
+	}
+	tempPath := tempFile.Name()
+	_, err = tempFile.Write(data)
+	if closeErr := tempFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tempPath, utils.GetFilePermissions())
+	}
+	if err != nil {
+		os.Remove(tempPath)
+		return "", "", fmt.Errorf("failed to write template: %w", err)
+	}
+	if _, _, err := templates.LoadTemplateWithPath(cfg, tempPath); err != nil {
+		os.Remove(tempPath)
+		return "", "", fmt.Errorf("template does not load from %s: %w", filepath.Dir(destPath), err)
+	}
+	if err := os.Rename(tempPath, destPath); err != nil {
+		os.Remove(tempPath)
+		return "", "", fmt.Errorf("failed to write template: %w", err)
+	}
+
+	return sourcePath, destPath, nil
+}
+
+// templateDestPath returns the path of a new template name in the user template directory
+// Names without a template extension get the extension of the source template
+func templateDestPath(name, sourceExt string) (string, error) {
+	if name == "" || strings.ContainsAny(name, "/\\") {
+		return "", fmt.Errorf("invalid template name '%s', expected a name without path separators", name)
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(name)); ext {
+	case ".json", ".yaml", ".yml":
+		if (ext == ".json") != (strings.ToLower(sourceExt) == ".json") {
+			return "", fmt.Errorf("cannot copy a %s template to %s, the file format would change", sourceExt, name)
+		}
+	default:
+		name += sourceExt
+	}
+
+	templateDir, err := cfg.EnsureTemplateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(templateDir, name), nil
+}
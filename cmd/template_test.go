@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDownloadedTemplatePath(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"chat.json", "vision.yaml", "ocr.yml", "both.json", "both.yaml"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("{}"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{"chat", filepath.Join(dir, "chat.json"), false},
+		{"chat.json", filepath.Join(dir, "chat.json"), false},
+		{"vision", filepath.Join(dir, "vision.yaml"), false},
+		{"vision.yaml", filepath.Join(dir, "vision.yaml"), false},
+		{"ocr", filepath.Join(dir, "ocr.yml"), false},
+		{"both", filepath.Join(dir, "both.json"), false},
+		{"both.yaml", filepath.Join(dir, "both.yaml"), false},
+		{filepath.Join(dir, "vision"), filepath.Join(dir, "vision.yaml"), false},
+		{"vision.json", "", true},
+		{"missing", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := downloadedTemplatePath(dir, tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("downloadedTemplatePath(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("downloadedTemplatePath(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
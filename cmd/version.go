@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+
+	"github.com/spf13/cobra"
+)
+
+// BuildInfo holds the version information injected into main at build time
+type BuildInfo struct {
+	Version   string
+	GitCommit string
+	BuildTime string
+	BuildBy   string
+}
+
+// buildInfo is reported by the version command, set by main with SetBuildInfo
+var buildInfo = BuildInfo{
+	Version:   "dev",
+	GitCommit: "unknown",
+	BuildTime: "unknown",
+	BuildBy:   "unknown",
+}
+
+// versionJSON is the --json output of the version command
+type versionJSON struct {
+	Version   string `json:"version"`
+	GitCommit string `json:"git_commit"`
+	BuildTime string `json:"build_time"`
+	BuildBy   string `json:"build_by"`
+	GoVersion string `json:"go_version"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+}
+
+// Version command flags
+var versionJSONFlag bool
+
+// Version command - displays build, Go runtime and platform information
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Display version, build and platform information",
+	Long: `Display the version, git commit, build time and builder of this binary,
+together with the Go runtime version and the operating system and architecture.
+
+Use --json for machine-readable output.
+
+Examples:
+  llm-caller version
+  llm-caller version --json
+  llm-caller --version`,
+	Args: cobra.NoArgs,
+	// The version command needs no configuration, so the root initialization is skipped
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error { return nil },
+	RunE:              runVersion,
+}
+
+func init() {
+	versionCmd.Flags().BoolVar(&versionJSONFlag, "json", false, "Print the version information as JSON")
+}
+
+// SetBuildInfo sets the build information reported by the version command
+func SetBuildInfo(info BuildInfo) {
+	buildInfo = info
+}
+
+func runVersion(cmd *cobra.Command, args []string) error {
+	if !versionJSONFlag {
+		PrintVersion(os.Stdout)
+		return nil
+	}
+
+	data, err := json.MarshalIndent(versionJSON{
+		Version:   buildInfo.Version,
+		GitCommit: buildInfo.GitCommit,
+		BuildTime: buildInfo.BuildTime,
+		BuildBy:   buildInfo.BuildBy,
+		GoVersion: runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal version information: %w", err)
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+// PrintVersion writes the version information as text to w
+func PrintVersion(w io.Writer) {
+	fmt.Fprintf(w, "llm-caller %s\n", buildInfo.Version)
+	fmt.Fprintf(w, "Git Commit: %s\n", buildInfo.GitCommit)
+	fmt.Fprintf(w, "Build Time: %s\n", buildInfo.BuildTime)
+	fmt.Fprintf(w, "Built By: %s\n", buildInfo.BuildBy)
+	fmt.Fprintf(w, "Go Version: %s\n", runtime.Version())
+	fmt.Fprintf(w, "Platform: %s/%s\n", runtime.GOOS, runtime.GOARCH)
+}
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"fmt"
 	"os"
 
 	"github.com/joho/godotenv"
@@ -17,12 +16,16 @@ var (
 )
 
 func main() {
-	// Handle version command
-	if len(os.Args) > 1 && (os.Args[1] == "version" || os.Args[1] == "--version") {
-		fmt.Printf("llm-caller %s\n", Version)
-		fmt.Printf("Git Commit: %s\n", GitCommit)
-		fmt.Printf("Build Time: %s\n", BuildTime)
-		fmt.Printf("Built By: %s\n", BuildBy)
+	cmd.SetBuildInfo(cmd.BuildInfo{
+		Version:   Version,
+		GitCommit: GitCommit,
+		BuildTime: BuildTime,
+		BuildBy:   BuildBy,
+	})
+
+	// Fast path for --version, the version command is handled by cobra
+	if len(os.Args) > 1 && os.Args[1] == "--version" {
+		cmd.PrintVersion(os.Stdout)
 		return
 	}
 
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/nodewee/llm-caller/pkg/utils"
 
@@ -18,21 +19,124 @@ const (
 	ConfigFile = "config"
 	// ConfigType is the type of the configuration file
 	ConfigType = "yaml"
+	// ProfileEnvVar is the environment variable selecting the active profile
+	ProfileEnvVar = "LLM_CALLER_PROFILE"
+	// DefaultProfile is the profile using the plain config.yaml file
+	DefaultProfile = "default"
 )
 
 // Configuration keys
 const (
 	KeyTemplateDir = "template_dir"
 	KeySecretFile  = "secret_file"
+	KeyLogFile     = "log_file"
+	KeyStrict      = "strict"
+	KeySaveHistory = "save_history"
 )
 
+// Provider configuration keys, used as provider.<name>.<key>
+const (
+	KeyProviderPrefix  = "provider"
+	KeyProviderBaseURL = "base_url"
+	KeyProviderHeaders = "headers"
+)
+
+// KeyDefaultPrefix prefixes default variable values, used as default.<name>
+const KeyDefaultPrefix = "default"
+
+// KeyRateLimitPrefix prefixes per-provider rate limits, used as ratelimit.<provider> (e.g. "3/s")
+const KeyRateLimitPrefix = "ratelimit"
+
+// KeyAliasPrefix prefixes template aliases, used as alias.<name> (e.g. alias.dc = deepseek-chat)
+const KeyAliasPrefix = "alias"
+
+// ProviderDefaults contains request settings shared by all templates of a provider
+type ProviderDefaults struct {
+	BaseURL string
+	Headers map[string]string
+}
+
+// ValidKeys returns a description of all settable configuration keys
+func ValidKeys() []string {
+	return []string{
+		KeyTemplateDir,
+		KeySecretFile,
+		KeyLogFile,
+		KeyStrict,
+		KeySaveHistory,
+		KeyProviderPrefix + ".<name>." + KeyProviderBaseURL,
+		KeyProviderPrefix + ".<name>." + KeyProviderHeaders + ".<header>",
+		KeyDefaultPrefix + ".<variable>",
+		KeyRateLimitPrefix + ".<provider>",
+		KeyAliasPrefix + ".<name>",
+	}
+}
+
+// ValidateKey checks that the key is a settable configuration key
+func ValidateKey(key string) error {
+	switch key {
+	case KeyTemplateDir, KeySecretFile, KeyLogFile, KeyStrict, KeySaveHistory:
+		return nil
+	}
+
+	parts := strings.Split(key, ".")
+	if len(parts) == 2 && (parts[0] == KeyDefaultPrefix || parts[0] == KeyRateLimitPrefix || parts[0] == KeyAliasPrefix) && parts[1] != "" {
+		return nil
+	}
+	if len(parts) >= 3 && parts[0] == KeyProviderPrefix && parts[1] != "" {
+		if len(parts) == 3 && parts[2] == KeyProviderBaseURL {
+			return nil
+		}
+		if len(parts) == 4 && parts[2] == KeyProviderHeaders && parts[3] != "" {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("invalid key: %s, valid keys are: %s", key, strings.Join(ValidKeys(), ", "))
+}
+
 // Config manages the application configuration
 type Config struct {
-	viper *viper.Viper
+	viper      *viper.Viper
+	profile    string
+	configFile string
+}
+
+// New creates a new config instance for a profile
+// An empty profile falls back to LLM_CALLER_PROFILE, then to the default profile (config.yaml)
+func New(profile string) (*Config, error) {
+	if profile == "" {
+		profile = utils.GetEnvironmentVariableCaseInsensitive(ProfileEnvVar)
+	}
+	if profile == "" {
+		profile = DefaultProfile
+	}
+	if strings.ContainsAny(profile, "/\\") || strings.HasPrefix(profile, ".") {
+		return nil, fmt.Errorf("invalid profile name: %s", profile)
+	}
+
+	configDir, err := utils.GetUserConfigDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user config directory: %w", err)
+	}
+
+	configName := profileConfigName(profile)
+	configFile := filepath.Join(configDir, configName+"."+ConfigType)
+	return load(configFile, profile)
 }
 
-// New creates a new config instance
-func New() (*Config, error) {
+// NewFromFile creates a config instance that reads and writes exactly the given file
+// Profiles and LLM_CALLER_PROFILE are ignored; a missing file is created
+func NewFromFile(configFile string) (*Config, error) {
+	configFile, err := filepath.Abs(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve config file path: %w", err)
+	}
+	return load(configFile, "")
+}
+
+// load reads configFile into a new viper instance, creating the file if it does not exist
+func load(configFile, profile string) (*Config, error) {
 	v := viper.New()
 
 	// Set defaults using cross-platform path handling
@@ -45,28 +149,41 @@ func New() (*Config, error) {
 	v.SetDefault(KeySecretFile, filepath.Join(configDir, "keys.json"))
 
 	// Setup config file with cross-platform directory permissions
-	if err := utils.CreateDirWithPlatformPermissions(configDir); err != nil {
+	if err := utils.CreateDirWithPlatformPermissions(filepath.Dir(configFile)); err != nil {
 		return nil, fmt.Errorf("failed to create config directory: %w", err)
 	}
 
-	v.SetConfigName(ConfigFile)
+	v.SetConfigFile(configFile)
 	v.SetConfigType(ConfigType)
-	v.AddConfigPath(configDir)
 
 	// Try to read the config file
-	if err := v.ReadInConfig(); err != nil {
-		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
-			// Config file was found but another error occurred
+	if _, err := os.Stat(configFile); err == nil {
+		if err := v.ReadInConfig(); err != nil {
 			return nil, fmt.Errorf("failed to read config file: %w", err)
 		}
+	} else if os.IsNotExist(err) {
 		// Config file not found, create it
-		configFile := filepath.Join(configDir, ConfigFile+"."+ConfigType)
-		if err := v.WriteConfigAs(configFile); err != nil {
+		if err := writeConfigFile(v, configFile); err != nil {
 			return nil, fmt.Errorf("failed to create config file: %w", err)
 		}
+	} else {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	return &Config{viper: v, profile: profile, configFile: configFile}, nil
+}
+
+// profileConfigName returns the config file name (without extension) for a profile
+func profileConfigName(profile string) string {
+	if profile == DefaultProfile {
+		return ConfigFile
 	}
+	return ConfigFile + "." + profile
+}
 
-	return &Config{viper: v}, nil
+// Profile returns the name of the active profile, empty when the config file was given with --config
+func (c *Config) Profile() string {
+	return c.profile
 }
 
 // Get returns the value associated with the key
@@ -79,10 +196,39 @@ func (c *Config) GetString(key string) string {
 	return c.viper.GetString(key)
 }
 
+// GetBool returns the value associated with the key as a boolean
+func (c *Config) GetBool(key string) bool {
+	return c.viper.GetBool(key)
+}
+
 // Set sets the value for the key
+// The config file is re-read under the config lock first, so concurrent writers do not lose each other's changes
 func (c *Config) Set(key string, value interface{}) error {
-	c.viper.Set(key, value)
-	return c.viper.WriteConfig()
+	return c.withLock(func() error {
+		if err := c.reload(); err != nil {
+			return err
+		}
+		c.viper.Set(key, value)
+		return writeConfigFile(c.viper, c.configFile)
+	})
+}
+
+// Save writes the current configuration to the config file, creating it if needed
+func (c *Config) Save() error {
+	return c.withLock(func() error {
+		return writeConfigFile(c.viper, c.configFile)
+	})
+}
+
+// reload re-reads the config file if it exists
+func (c *Config) reload() error {
+	if _, err := os.Stat(c.configFile); os.IsNotExist(err) {
+		return nil
+	}
+	if err := c.viper.ReadInConfig(); err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	return nil
 }
 
 // List returns all the configuration settings
@@ -92,16 +238,15 @@ func (c *Config) List() map[string]interface{} {
 
 // Delete removes the value for the key
 func (c *Config) Delete(key string) error {
-	// Get the current config file used
-	configFile := c.viper.ConfigFileUsed()
-	if configFile == "" {
-		// If no config file is used, construct the path
-		configDir, err := utils.GetUserConfigDir()
-		if err != nil {
-			return fmt.Errorf("failed to get user config directory: %w", err)
-		}
-		configFile = filepath.Join(configDir, ConfigFile+"."+ConfigType)
-	}
+	return c.withLock(func() error {
+		return c.delete(key)
+	})
+}
+
+// delete removes the value for the key, the caller holds the config lock
+func (c *Config) delete(key string) error {
+	// Use the config file of the active profile
+	configFile := c.configFile
 
 	// Read the existing config file to check if the key exists in the file
 	data, err := os.ReadFile(configFile)
@@ -121,14 +266,11 @@ func (c *Config) Delete(key string) error {
 		existingConfig = tempViper.AllSettings()
 	}
 
-	// Check if the key exists in the user configuration (not just defaults)
-	if _, exists := existingConfig[key]; !exists {
+	// Remove the key from the user configuration (not just defaults)
+	if !deleteNestedKey(existingConfig, strings.ToLower(key)) {
 		return fmt.Errorf("key %s not found in configuration", key)
 	}
 
-	// Remove the key from the configuration
-	delete(existingConfig, key)
-
 	// Create a new viper instance and set only the remaining user configuration
 	newViper := viper.New()
 	for k, v := range existingConfig {
@@ -136,12 +278,12 @@ func (c *Config) Delete(key string) error {
 	}
 
 	// Set config file info
-	newViper.SetConfigName(ConfigFile)
+	newViper.SetConfigName(profileConfigName(c.profile))
 	newViper.SetConfigType(ConfigType)
 	newViper.AddConfigPath(filepath.Dir(configFile))
 
 	// Write the updated configuration
-	if err := newViper.WriteConfigAs(configFile); err != nil {
+	if err := writeConfigFile(newViper, configFile); err != nil {
 		return fmt.Errorf("failed to write config: %w", err)
 	}
 
@@ -153,14 +295,57 @@ func (c *Config) Delete(key string) error {
 	return nil
 }
 
-// GetConfigFilePath returns the path to the configuration file
-func (c *Config) GetConfigFilePath() string {
-	configDir, err := utils.GetUserConfigDir()
-	if err != nil {
+// GetProviderDefaults returns the configured defaults for the given provider
+func (c *Config) GetProviderDefaults(provider string) ProviderDefaults {
+	defaults := ProviderDefaults{Headers: map[string]string{}}
+	if provider == "" {
+		return defaults
+	}
+
+	prefix := KeyProviderPrefix + "." + strings.ToLower(provider) + "."
+	defaults.BaseURL = c.viper.GetString(prefix + KeyProviderBaseURL)
+	for name, value := range c.viper.GetStringMapString(prefix + KeyProviderHeaders) {
+		defaults.Headers[name] = value
+	}
+
+	return defaults
+}
+
+// GetVariableDefaults returns the configured default variable values
+// Variable names are stored in lower case by the config file format
+func (c *Config) GetVariableDefaults() map[string]string {
+	return c.viper.GetStringMapString(KeyDefaultPrefix)
+}
+
+// GetRateLimit returns the configured rate limit of a provider (e.g. "3/s"), empty if none is set
+func (c *Config) GetRateLimit(provider string) string {
+	if provider == "" {
 		return ""
 	}
+	return c.viper.GetString(KeyRateLimitPrefix + "." + strings.ToLower(provider))
+}
+
+// GetTemplateAliases returns the configured template aliases by alias name
+// Alias names are stored in lower case by the config file format
+func (c *Config) GetTemplateAliases() map[string]string {
+	return c.viper.GetStringMapString(KeyAliasPrefix)
+}
+
+// ResolveTemplateAlias returns the template an alias refers to, or name itself if it is not an alias
+// Paths are never aliases
+func (c *Config) ResolveTemplateAlias(name string) string {
+	if name == "" || strings.ContainsAny(name, "/\\") {
+		return name
+	}
+	if target := c.viper.GetString(KeyAliasPrefix + "." + strings.ToLower(name)); target != "" {
+		return target
+	}
+	return name
+}
 
-	return filepath.Join(configDir, ConfigFile+"."+ConfigType)
+// GetConfigFilePath returns the path to the configuration file of the active profile
+func (c *Config) GetConfigFilePath() string {
+	return c.configFile
 }
 
 // GetDefaultTemplateDir returns the default template directory path
@@ -181,3 +366,27 @@ func (c *Config) EnsureTemplateDir() (string, error) {
 	}
 	return templateDir, nil
 }
+
+// deleteNestedKey removes a dot-separated key from nested settings, pruning empty parents
+// It returns false if the key does not exist
+func deleteNestedKey(settings map[string]interface{}, key string) bool {
+	parts := strings.SplitN(key, ".", 2)
+	value, exists := settings[parts[0]]
+	if !exists {
+		return false
+	}
+
+	if len(parts) == 1 {
+		delete(settings, parts[0])
+		return true
+	}
+
+	child, ok := value.(map[string]interface{})
+	if !ok || !deleteNestedKey(child, parts[1]) {
+		return false
+	}
+	if len(child) == 0 {
+		delete(settings, parts[0])
+	}
+	return true
+}
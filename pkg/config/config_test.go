@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestConcurrentSetKeepsEveryKey(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	configFile := filepath.Join(dir, "config.yaml")
+	if _, err := NewFromFile(configFile); err != nil {
+		t.Fatalf("NewFromFile: %v", err)
+	}
+
+	// Each writer has its own Config, like separate llm-caller processes
+	const writers = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			cfg, err := NewFromFile(configFile)
+			if err != nil {
+				errs <- err
+				return
+			}
+			errs <- cfg.Set(fmt.Sprintf("default.var%d", i), fmt.Sprintf("value%d", i))
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Set: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	var settings struct {
+		Default map[string]string `yaml:"default"`
+	}
+	if err := yaml.Unmarshal(data, &settings); err != nil {
+		t.Fatalf("config file is not valid YAML: %v\n%s", err, data)
+	}
+	for i := 0; i < writers; i++ {
+		key := fmt.Sprintf("var%d", i)
+		if got, want := settings.Default[key], fmt.Sprintf("value%d", i); got != want {
+			t.Errorf("default.%s = %q, want %q", key, got, want)
+		}
+	}
+	if _, err := os.Stat(configFile + ".lock"); !os.IsNotExist(err) {
+		t.Errorf("lock file left behind: %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// homePrefix marks paths relative to the home directory in exported settings
+const homePrefix = "~/"
+
+// pathKeys are the settings holding file paths, written relative to the home directory when exported
+var pathKeys = []string{KeyTemplateDir, KeySecretFile, KeyLogFile}
+
+// ExportSettings returns the effective settings for 'config export'
+// Paths inside the home directory are written as ~/..., so the export works on a machine with another home directory
+func (c *Config) ExportSettings() map[string]interface{} {
+	settings := c.viper.AllSettings()
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return settings
+	}
+	for _, key := range pathKeys {
+		path, ok := settings[key].(string)
+		if !ok {
+			continue
+		}
+		if rel, err := filepath.Rel(home, path); err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+			settings[key] = homePrefix + filepath.ToSlash(rel)
+		}
+	}
+	return settings
+}
+
+// ImportValues flattens exported settings into dotted keys, validating each key
+// Paths written as ~/... are expanded to the current home directory
+func ImportValues(settings map[string]interface{}) (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	flattenSettings("", settings, values)
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := ValidateKey(key); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, key := range pathKeys {
+		path, ok := values[key].(string)
+		if !ok || !strings.HasPrefix(path, homePrefix) {
+			continue
+		}
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get home directory for %s: %w", key, err)
+		}
+		values[key] = filepath.Join(home, filepath.FromSlash(strings.TrimPrefix(path, homePrefix)))
+	}
+	return values, nil
+}
+
+// flattenSettings adds the leaf values of nested settings to values under their dotted keys
+func flattenSettings(prefix string, settings map[string]interface{}, values map[string]interface{}) {
+	for key, value := range settings {
+		fullKey := strings.ToLower(key)
+		if prefix != "" {
+			fullKey = prefix + "." + fullKey
+		}
+		if nested, ok := value.(map[string]interface{}); ok {
+			flattenSettings(fullKey, nested, values)
+			continue
+		}
+		values[fullKey] = value
+	}
+}
+
+// SetAll sets several values and writes the config file once
+func (c *Config) SetAll(values map[string]interface{}) error {
+	return c.withLock(func() error {
+		if err := c.reload(); err != nil {
+			return err
+		}
+		for key, value := range values {
+			c.viper.Set(key, value)
+		}
+		return writeConfigFile(c.viper, c.configFile)
+	})
+}
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/nodewee/llm-caller/pkg/utils"
+
+	"github.com/spf13/viper"
+)
+
+// withLock runs fn while holding the lock file of the config file, so concurrent processes serialize their writes
+func (c *Config) withLock(fn func() error) error {
+	unlock, err := utils.LockFile(c.configFile)
+	if err != nil {
+		return fmt.Errorf("failed to lock config file: %w", err)
+	}
+	defer unlock()
+	return fn()
+}
+
+// writeConfigFile writes the settings of v to path atomically
+// The config is written to a temporary file in the same directory and renamed over path, so readers never see a partial file
+func writeConfigFile(v *viper.Viper, path string) error {
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*."+ConfigType)
+	if err != nil {
+		return fmt.Errorf("failed to create temporary config file: %w", err)
+	}
+	tmpPath := file.Name()
+	file.Close()
+
+	if err := v.WriteConfigAs(tmpPath); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Chmod(tmpPath, utils.GetFilePermissions()); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
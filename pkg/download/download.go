@@ -1,6 +1,7 @@
 package download
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"net/http"
@@ -10,12 +11,14 @@ import (
 	"strings"
 	"time"
 
+	"github.com/nodewee/llm-caller/pkg/templates"
 	"github.com/nodewee/llm-caller/pkg/utils"
 )
 
 // GitHubDownloader handles downloading files from GitHub URLs
 type GitHubDownloader struct {
 	client *http.Client
+	quiet  bool
 }
 
 // Mirror site configuration
@@ -33,12 +36,32 @@ type GitHubInfo struct {
 }
 
 // NewGitHubDownloader creates a new GitHub downloader
-func NewGitHubDownloader() *GitHubDownloader {
+// An empty proxyURL uses the proxy settings from the environment
+func NewGitHubDownloader(proxyURL string) (*GitHubDownloader, error) {
+	transport, err := utils.NewHTTPTransport(proxyURL)
+	if err != nil {
+		return nil, err
+	}
+
 	return &GitHubDownloader{
 		client: &http.Client{
-			Timeout: 30 * time.Second,
+			Transport: transport,
+			Timeout:   30 * time.Second,
 		},
+	}, nil
+}
+
+// SetQuiet suppresses the download progress messages
+func (d *GitHubDownloader) SetQuiet(quiet bool) {
+	d.quiet = quiet
+}
+
+// printf prints a progress message unless the downloader is quiet
+func (d *GitHubDownloader) printf(format string, args ...interface{}) {
+	if d.quiet {
+		return
 	}
+	fmt.Printf(format, args...)
 }
 
 // parseGitHubURL extracts owner, repo, branch, and file information from a GitHub URL
@@ -169,9 +192,9 @@ func (d *GitHubDownloader) DownloadTemplate(githubURL, templateDir string) (stri
 		return "", fmt.Errorf("failed to parse GitHub URL: %w", err)
 	}
 
-	// Ensure filename has .json extension
+	// Keep a template extension (.json, .yaml or .yml), default to .json
 	filename := info.FileName
-	if !strings.HasSuffix(filename, ".json") {
+	if !templates.IsTemplateFile(filename) {
 		filename += ".json"
 	}
 
@@ -181,53 +204,151 @@ func (d *GitHubDownloader) DownloadTemplate(githubURL, templateDir string) (stri
 	}
 
 	destPath := filepath.Join(templateDir, filename)
+	if err := d.downloadWithFallback(githubURL, info, destPath); err != nil {
+		return "", err
+	}
+	return destPath, nil
+}
 
+// downloadWithFallback downloads a GitHub file to destPath, falling back to the mirror site
+func (d *GitHubDownloader) downloadWithFallback(githubURL string, info *GitHubInfo, destPath string) error {
 	// First, try to download from GitHub
 	rawURL, err := d.ConvertToRawURL(githubURL)
 	if err != nil {
-		return "", fmt.Errorf("failed to convert GitHub URL: %w", err)
+		return fmt.Errorf("failed to convert GitHub URL: %w", err)
 	}
 
-	fmt.Printf("Downloading from GitHub: %s\n", rawURL)
+	d.printf("Downloading from GitHub: %s\n", rawURL)
 	githubErr := d.downloadFromURL(rawURL, destPath)
 	if githubErr == nil {
-		fmt.Printf("Successfully downloaded from GitHub\n")
-		return destPath, nil
+		d.printf("Successfully downloaded from GitHub\n")
+		return nil
 	}
 
 	// GitHub download failed, try mirror site
-	fmt.Printf("GitHub download failed (%v), trying mirror site...\n", githubErr)
+	d.printf("GitHub download failed (%v), trying mirror site...\n", githubErr)
 	mirrorURL := d.buildMirrorURL(info)
-	fmt.Printf("Downloading from mirror: %s\n", mirrorURL)
+	d.printf("Downloading from mirror: %s\n", mirrorURL)
 
 	mirrorErr := d.downloadFromURL(mirrorURL, destPath)
 	if mirrorErr != nil {
-		return "", fmt.Errorf("failed to download from both GitHub and mirror site. GitHub error: %v, Mirror error: %v",
+		return fmt.Errorf("failed to download from both GitHub and mirror site. GitHub error: %v, Mirror error: %v",
 			githubErr, mirrorErr)
 	}
 
-	fmt.Printf("Successfully downloaded from mirror site\n")
-	return destPath, nil
+	d.printf("Successfully downloaded from mirror site\n")
+	return nil
 }
 
-// ValidateTemplateFile validates that the downloaded file is a valid JSON template
-func (d *GitHubDownloader) ValidateTemplateFile(filePath string) error {
-	// Read the file
-	data, err := os.ReadFile(filePath)
+// UpdateTemplate re-downloads a template from the source URL recorded when it was downloaded
+// The file is only replaced if the new content is a valid template; it returns whether the content changed
+func (d *GitHubDownloader) UpdateTemplate(templatePath string) (bool, error) {
+	sourceURL, err := LoadSourceURL(templatePath)
 	if err != nil {
-		return fmt.Errorf("failed to read file: %w", err)
+		return false, err
 	}
 
-	// Basic validation - check if it's valid JSON and contains required fields
-	if len(data) == 0 {
-		return fmt.Errorf("file is empty")
+	info, err := d.parseGitHubURL(sourceURL)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse GitHub URL: %w", err)
 	}
 
-	// Check if it looks like JSON
-	content := strings.TrimSpace(string(data))
-	if !strings.HasPrefix(content, "{") || !strings.HasSuffix(content, "}") {
-		return fmt.Errorf("file does not appear to be a JSON file")
+	oldData, err := os.ReadFile(templatePath)
+	if err != nil {
+		return false, fmt.Errorf("failed to read template: %w", err)
 	}
 
-	return nil
+	// Download next to the template so the final rename stays on the same filesystem,
+	// keeping its extension so the content is validated in the template's format
+	tmpPath := templates.TrimTemplateExt(templatePath) + ".download" + filepath.Ext(templatePath)
+	defer os.Remove(tmpPath)
+	if err := d.downloadWithFallback(sourceURL, info, tmpPath); err != nil {
+		return false, err
+	}
+	if err := d.ValidateTemplateFile(tmpPath); err != nil {
+		return false, fmt.Errorf("downloaded file is not a valid template: %w", err)
+	}
+
+	newData, err := os.ReadFile(tmpPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to read downloaded template: %w", err)
+	}
+	if bytes.Equal(oldData, newData) {
+		return false, nil
+	}
+
+	if err := os.Rename(tmpPath, templatePath); err != nil {
+		return false, fmt.Errorf("failed to replace template: %w", err)
+	}
+	return true, nil
+}
+
+// FetchUpstream downloads the current content of a downloaded template from its recorded source URL
+// The template itself is not modified; it returns the content and the source URL
+func (d *GitHubDownloader) FetchUpstream(templatePath string) ([]byte, string, error) {
+	sourceURL, err := LoadSourceURL(templatePath)
+	if err != nil {
+		return nil, "", err
+	}
+
+	info, err := d.parseGitHubURL(sourceURL)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to parse GitHub URL: %w", err)
+	}
+
+	tmpFile, err := os.CreateTemp("", "llm-caller-upstream-*"+filepath.Ext(templatePath))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	tmpPath := tmpFile.Name()
+	tmpFile.Close()
+	defer os.Remove(tmpPath)
+
+	if err := d.downloadWithFallback(sourceURL, info, tmpPath); err != nil {
+		return nil, "", err
+	}
+	data, err := os.ReadFile(tmpPath)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read downloaded template: %w", err)
+	}
+	return data, sourceURL, nil
+}
+
+// SourceURLPath returns the path of the sidecar file recording a template's download URL
+func SourceURLPath(templatePath string) string {
+	return templates.TrimTemplateExt(templatePath) + ".url"
+}
+
+// SaveSourceURL records the URL a template was downloaded from
+func SaveSourceURL(templatePath, sourceURL string) error {
+	return os.WriteFile(SourceURLPath(templatePath), []byte(sourceURL+"\n"), utils.GetFilePermissions())
+}
+
+// LoadSourceURL returns the URL a template was downloaded from
+func LoadSourceURL(templatePath string) (string, error) {
+	data, err := os.ReadFile(SourceURLPath(templatePath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("no source URL recorded for %s (download it again with 'template download')", filepath.Base(templatePath))
+		}
+		return "", fmt.Errorf("failed to read source URL: %w", err)
+	}
+
+	sourceURL := strings.TrimSpace(string(data))
+	if sourceURL == "" {
+		return "", fmt.Errorf("source URL file %s is empty", SourceURLPath(templatePath))
+	}
+	return sourceURL, nil
+}
+
+// ValidateTemplateFile validates that the downloaded file is a valid template
+// The file is parsed like a local template, as JSON or YAML according to its extension
+func (d *GitHubDownloader) ValidateTemplateFile(filePath string) error {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	_, err = templates.ParseTemplateFile(filePath, data)
+	return err
 }
@@ -0,0 +1,56 @@
+package download
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateTemplateFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		wantErr bool
+	}{
+		{"json template", "chat.json", `{"provider": "openai", "request": {"url": "https://api.example.com", "body": {}}}`, false},
+		{"json with comments", "chat.json", "// chat\n{\"provider\": \"openai\", \"request\": {\"url\": \"https://api.example.com\", \"body\": {}}}", false},
+		{"yaml template", "chat.yaml", "provider: openai\nrequest:\n  url: https://api.example.com\n  body: {}\n", false},
+		{"yml template", "chat.yml", "provider: openai\nrequest:\n  url: https://api.example.com\n  body: {}\n", false},
+		{"empty file", "chat.json", "", true},
+		{"html page", "chat.json", "<html><body>Not Found</body></html>", true},
+		{"json object that is not a template", "chat.json", `{"message": "rate limited"}`, true},
+		{"yaml in a json file", "chat.json", "provider: openai\nrequest:\n  url: https://api.example.com\n", true},
+		{"yaml that is not a mapping", "chat.yaml", "- one\n- two\n", true},
+	}
+	downloader := &GitHubDownloader{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			err := downloader.ValidateTemplateFile(path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateTemplateFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSourceURLPath(t *testing.T) {
+	tests := []struct {
+		templatePath string
+		want         string
+	}{
+		{"/templates/chat.json", "/templates/chat.url"},
+		{"/templates/chat.yaml", "/templates/chat.url"},
+		{"/templates/chat.yml", "/templates/chat.url"},
+		{"/templates/chat.v2.json", "/templates/chat.v2.url"},
+	}
+	for _, tt := range tests {
+		if got := SourceURLPath(tt.templatePath); got != tt.want {
+			t.Errorf("SourceURLPath(%q) = %q, want %q", tt.templatePath, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package llm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nodewee/llm-caller/pkg/templates"
+)
+
+// encodeRequestBody encodes the template body according to its body type
+// It returns the encoded body and the matching Content-Type
+func encodeRequestBody(request templates.RequestConfig) ([]byte, string, error) {
+	switch request.BodyType {
+	case "", templates.BodyTypeJSON:
+		data, err := json.Marshal(request.Body)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to marshal request body: %w", err)
+		}
+		return data, "application/json", nil
+
+	case templates.BodyTypeForm:
+		values, err := encodeFormBody(request.Body)
+		if err != nil {
+			return nil, "", err
+		}
+		return []byte(values.Encode()), "application/x-www-form-urlencoded", nil
+
+	case templates.BodyTypeRaw:
+		content, err := templates.RawBodyContent(request.Body)
+		if err != nil {
+			return nil, "", err
+		}
+		return []byte(content), "text/plain; charset=utf-8", nil
+
+	case templates.BodyTypeMultipart:
+		return encodeMultipartBody(request.Body)
+
+	default:
+		return nil, "", fmt.Errorf("unsupported body type '%s'", request.BodyType)
+	}
+}
+
+// encodeFormBody converts a body map to URL-encoded form values
+// Arrays become repeated keys, objects are sent as JSON strings
+func encodeFormBody(body map[string]interface{}) (url.Values, error) {
+	keys := make([]string, 0, len(body))
+	for key := range body {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	values := url.Values{}
+	for _, key := range keys {
+		if items, ok := body[key].([]interface{}); ok {
+			for _, item := range items {
+				value, err := formValue(item)
+				if err != nil {
+					return nil, fmt.Errorf("failed to encode form field '%s': %w", key, err)
+				}
+				values.Add(key, value)
+			}
+			continue
+		}
+
+		value, err := formValue(body[key])
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode form field '%s': %w", key, err)
+		}
+		values.Add(key, value)
+	}
+	return values, nil
+}
+
+// encodeMultipartBody builds a multipart/form-data body with plain fields and file parts
+func encodeMultipartBody(body map[string]interface{}) ([]byte, string, error) {
+	keys := make([]string, 0, len(body))
+	for key := range body {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	for _, key := range keys {
+		file, isFile, err := templates.ParseMultipartFile(key, body[key])
+		if err != nil {
+			return nil, "", err
+		}
+
+		if !isFile {
+			value, err := formValue(body[key])
+			if err != nil {
+				return nil, "", fmt.Errorf("failed to encode multipart field '%s': %w", key, err)
+			}
+			if err := writer.WriteField(key, value); err != nil {
+				return nil, "", fmt.Errorf("failed to write multipart field '%s': %w", key, err)
+			}
+			continue
+		}
+
+		if err := writeMultipartFile(writer, key, file); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, "", fmt.Errorf("failed to finish multipart body: %w", err)
+	}
+	return buf.Bytes(), writer.FormDataContentType(), nil
+}
+
+// writeMultipartFile writes a file part from a local path or from inline content
+func writeMultipartFile(writer *multipart.Writer, field string, file *templates.MultipartFile) error {
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+		escapeQuotes(field), escapeQuotes(file.Filename)))
+	header.Set("Content-Type", file.ContentType)
+
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return fmt.Errorf("failed to create file part '%s': %w", field, err)
+	}
+
+	if file.Path == "" {
+		_, err = io.WriteString(part, file.Content)
+	} else {
+		var source *os.File
+		source, err = os.Open(file.Path)
+		if err != nil {
+			return fmt.Errorf("failed to open file %s for part '%s': %w", file.Path, field, err)
+		}
+		defer source.Close()
+		_, err = io.Copy(part, source)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write file part '%s': %w", field, err)
+	}
+	return nil
+}
+
+// escapeQuotes escapes a value for use in a quoted Content-Disposition parameter
+func escapeQuotes(value string) string {
+	return strings.NewReplacer("\\", "\\\\", `"`, "\\\"").Replace(value)
+}
+
+// formValue converts a single body value to its form string representation
+func formValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+}
@@ -2,109 +2,522 @@ package llm
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/nodewee/llm-caller/pkg/ratelimit"
 	"github.com/nodewee/llm-caller/pkg/templates"
+	"github.com/nodewee/llm-caller/pkg/transform"
+	"github.com/nodewee/llm-caller/pkg/utils"
 )
 
 // GenericClient is a generic HTTP client for calling LLM APIs
 type GenericClient struct {
-	APIKey string
-	Client *http.Client
+	APIKey           string
+	Client           *http.Client
+	Logger           *log.Logger
+	MaxResponseBytes int64
+	RateLimiter      *ratelimit.Limiter
+	SigningSecret    string
+	UserAgent        string
+	Context          context.Context
+	OnDelta          func(delta string)
+}
+
+// DefaultUserAgent is sent when neither the template nor the options set a User-Agent
+const DefaultUserAgent = "https://github.com/nodewee/llm-caller"
+
+// DefaultMaxResponseBytes is the default limit for the size of a response body
+const DefaultMaxResponseBytes = 32 << 20
+
+// ClientOptions contains optional settings for the HTTP client
+type ClientOptions struct {
+	// ProxyURL is an explicit proxy (http, https or socks5); when empty the environment is used
+	ProxyURL string
+
+	// CACertFile is a PEM file with additional CA certificates to trust
+	CACertFile string
+
+	// Insecure disables TLS certificate verification
+	Insecure bool
+
+	// MaxIdleConnsPerHost is the number of idle connections kept per host for reuse, 0 for Go's default of 2
+	// Concurrent requests beyond it open new connections, each with its own TLS handshake
+	MaxIdleConnsPerHost int
+
+	// MaxResponseBytes limits the response body size, 0 means unlimited
+	MaxResponseBytes int64
+
+	// Logger receives request/response lifecycle details when set (e.g. for --verbose)
+	Logger *log.Logger
+
+	// RateLimiter delays requests to stay within the provider's configured rate, shared across processes
+	RateLimiter *ratelimit.Limiter
+
+	// SigningSecret is the secret of the template's signing block
+	SigningSecret string
+
+	// UserAgent overrides the User-Agent header, including one set by the template
+	UserAgent string
+
+	// Context cancels requests in flight, e.g. on Ctrl-C; nil means requests are never cancelled
+	Context context.Context
+
+	// OnDelta receives the content deltas of a streamed response as they arrive, e.g. to print them live
+	OnDelta func(delta string)
 }
 
 // NewGenericClient creates a new generic client
-func NewGenericClient(apiKey string) (*GenericClient, error) {
+// Clients with the same proxy, TLS and pooling options share one transport and its idle connections
+func NewGenericClient(apiKey string, opts ClientOptions) (*GenericClient, error) {
+	transport, err := sharedTransport(opts)
+	if err != nil {
+		return nil, err
+	}
+
 	// Allow empty API key for local LLMs that don't require authentication
 	return &GenericClient{
-		APIKey: apiKey,
-		Client: &http.Client{},
+		APIKey:           apiKey,
+		Client:           &http.Client{Transport: transport},
+		Logger:           opts.Logger,
+		MaxResponseBytes: opts.MaxResponseBytes,
+		RateLimiter:      opts.RateLimiter,
+		SigningSecret:    opts.SigningSecret,
+		UserAgent:        opts.UserAgent,
+		Context:          opts.Context,
+		OnDelta:          opts.OnDelta,
 	}, nil
 }
 
+// context returns the context that cancels the client's requests and rate limit waits
+func (c *GenericClient) context() context.Context {
+	if c.Context == nil {
+		return context.Background()
+	}
+	return c.Context
+}
+
+// logf writes a lifecycle message if a logger is configured
+func (c *GenericClient) logf(format string, args ...interface{}) {
+	if c.Logger != nil {
+		c.Logger.Printf(format, args...)
+	}
+}
+
+// redact hides the API key and the signing secret in values written to the log or returned in errors
+func (c *GenericClient) redact(value string) string {
+	return utils.Redact(value, c.APIKey, c.SigningSecret)
+}
+
+// Result contains the extracted content and the parsed API response
+type Result struct {
+	// Content is the extracted and transformed response content
+	Content string
+
+	// Response is the parsed JSON response body, usually an object but an array for some APIs
+	Response interface{}
+
+	// StatusCode is the HTTP status code of the response
+	StatusCode int
+
+	// Body is the unmodified response body, after decompression
+	Body []byte
+
+	// Usage is the token usage reported by the API, summed over all pages of a paginated call
+	// It is nil when the API reports no usage
+	Usage *Usage
+}
+
+// ResponseError is returned when the API responded but the call failed
+// It carries the HTTP status code and the raw response body so callers can report them without parsing the message
+type ResponseError struct {
+	StatusCode int
+	Body       []byte
+	Err        error
+}
+
+func (e *ResponseError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ResponseError) Unwrap() error {
+	return e.Err
+}
+
 // Call calls the LLM API with the given template
-func (c *GenericClient) Call(template *templates.Template) (string, error) {
-	// Marshal the request body to JSON
-	reqBytes, err := json.Marshal(template.Request.Body)
+// Secrets are removed from returned errors, e.g. an API key echoed in an error response
+// With a pagination block the following pages are requested as well, see callPages
+func (c *GenericClient) Call(template *templates.Template) (*Result, error) {
+	result, err := c.callPage(template)
+	if err != nil || template.Pagination == nil {
+		return result, err
+	}
+	return c.callPages(template, result)
+}
+
+// callPages requests the pages following the first result while the responses carry a next page cursor
+// The contents of all pages are joined with newlines and their usage is summed,
+// the other result fields are those of the last page
+func (c *GenericClient) callPages(template *templates.Template, first *Result) (*Result, error) {
+	pagination := template.Pagination
+	pages := []*Result{first}
+	result := first
+	for page := 2; ; page++ {
+		cursor, err := lookupResponsePath(result.Response, pagination.NextPath)
+		if err != nil || cursor == nil || cursor == "" {
+			break
+		}
+		if page > pagination.PageLimit() {
+			c.logf("Stopping pagination at max_pages %d, the last response has a next cursor", pagination.PageLimit())
+			break
+		}
+
+		next := template.Clone()
+		if err := pagination.SetCursor(next, cursor); err != nil {
+			return nil, err
+		}
+		c.logf("Requesting page %d", page)
+		result, err = c.callPage(next)
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %w", page, err)
+		}
+		pages = append(pages, result)
+	}
+
+	aggregated := *result
+	aggregated.Usage = nil
+	contents := make([]string, 0, len(pages))
+	for _, page := range pages {
+		contents = append(contents, page.Content)
+		if page.Usage != nil {
+			if aggregated.Usage == nil {
+				aggregated.Usage = &Usage{}
+			}
+			aggregated.Usage.Add(*page.Usage)
+		}
+	}
+	aggregated.Content = strings.Join(contents, "\n")
+	return &aggregated, nil
+}
+
+// callPage sends one request of the template and extracts its content
+func (c *GenericClient) callPage(template *templates.Template) (*Result, error) {
+	resp, start, err := c.send(template)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request body: %w", err)
+		return nil, utils.RedactError(err, c.APIKey, c.SigningSecret)
 	}
+	defer resp.Body.Close()
 
-	// Create HTTP request
-	httpReq, err := http.NewRequest(template.Request.Method, template.Request.URL, bytes.NewBuffer(reqBytes))
+	result, body, err := c.handleResponse(template, resp, start)
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return nil, &ResponseError{
+			StatusCode: resp.StatusCode,
+			Body:       []byte(c.redact(string(body))),
+			Err:        utils.RedactError(err, c.APIKey, c.SigningSecret),
+		}
 	}
+	return result, nil
+}
 
-	// Set headers from template
-	for key, value := range template.Request.Headers {
-		httpReq.Header.Set(key, value)
+// Ping sends the template's request, or a GET request to its health_url, and returns the HTTP status code
+// The response body is discarded, so no content is extracted
+func (c *GenericClient) Ping(template *templates.Template) (int, error) {
+	if template.HealthURL != "" {
+		template = template.Clone()
+		template.Request.Method = http.MethodGet
+		template.Request.URL = template.HealthURL
+		template.Request.Body = nil
 	}
 
-	// Always add/overwrite User-Agent header
-	httpReq.Header.Set("User-Agent", "https://github.com/nodewee/llm-caller")
+	resp, _, err := c.send(template)
+	if err != nil {
+		return 0, utils.RedactError(err, c.APIKey, c.SigningSecret)
+	}
+	defer resp.Body.Close()
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+	c.logf("Response status: %s", resp.Status)
+	return resp.StatusCode, nil
+}
+
+// send builds the HTTP request of the template and sends it, returning the response and the time it was sent
+func (c *GenericClient) send(template *templates.Template) (*http.Response, time.Time, error) {
+	httpReq, reqBytes, err := c.buildRequest(template)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	// Wait for the rate limiter before sending
+	if c.RateLimiter != nil {
+		waited, err := c.RateLimiter.Wait(c.context())
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		if waited > 0 {
+			c.logf("Rate limited, waited %s", waited.Round(time.Millisecond))
+		}
+	}
+
+	// Sign the request last, so the timestamp is not delayed by the rate limiter
+	if err := c.signRequest(template, httpReq, reqBytes); err != nil {
+		return nil, time.Time{}, err
+	}
+
+	c.logRequest(httpReq, len(reqBytes))
 
 	// Send the request
+	start := time.Now()
 	resp, err := c.Client.Do(httpReq)
 	if err != nil {
-		return "", fmt.Errorf("failed to send request: %w", err)
+		return nil, time.Time{}, fmt.Errorf("failed to send request: %w", err)
+	}
+	return resp, start, nil
+}
+
+// buildRequest creates the HTTP request of the template with its encoded body and all headers except the signature
+func (c *GenericClient) buildRequest(template *templates.Template) (*http.Request, []byte, error) {
+	// Encode the request body according to the body type, GET and HEAD requests have no body
+	var reqBody io.Reader
+	var reqBytes []byte
+	var contentType string
+	if !template.Request.IsBodyless() {
+		var err error
+		reqBytes, contentType, err = encodeRequestBody(template.Request)
+		if err != nil {
+			return nil, nil, err
+		}
+		reqBody = bytes.NewBuffer(reqBytes)
 	}
-	defer resp.Body.Close()
 
-	// Read the response body
-	body, err := io.ReadAll(resp.Body)
+	// Create HTTP request, cancelled together with the client's context
+	httpReq, err := http.NewRequestWithContext(c.context(), template.Request.Method, template.Request.URL, reqBody)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response body: %w", err)
+		return nil, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	// Set headers from template
+	for key, value := range template.Request.Headers {
+		httpReq.Header.Set(key, value)
 	}
 
-	// Check for error response
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API request failed (status %d): %s", resp.StatusCode, string(body))
+	// Set the authentication header from the API key unless the template sets that header itself
+	if template.Auth != nil && c.APIKey != "" {
+		name, value := template.Auth.Header(c.APIKey)
+		if httpReq.Header.Get(name) == "" {
+			httpReq.Header.Set(name, value)
+		}
 	}
 
-	// Use auto-detection if enabled, otherwise use the specified path
-	var result string
-	if template.Response.AutoDetect {
-		result, err = c.autoDetectResponseContent(body, template.Response.ResponseFieldName)
-		if err != nil {
-			// Fall back to path-based extraction if auto-detection fails
-			result, err = c.extractResponseContentByPath(body, template.Response.Path)
-			if err != nil {
-				// Preserve the detailed error from extractResponseContentByPath
-				return "", err
+	// Set the Content-Type matching the body type unless the template specifies one
+	// Multipart bodies always use the generated Content-Type since it carries the boundary
+	if contentType != "" && (httpReq.Header.Get("Content-Type") == "" || template.Request.BodyType == templates.BodyTypeMultipart) {
+		httpReq.Header.Set("Content-Type", contentType)
+	}
+
+	// Ask for the body format of non-JSON response types unless the template specifies one
+	if accept := template.Response.AcceptHeader(); accept != "" && httpReq.Header.Get("Accept") == "" {
+		httpReq.Header.Set("Accept", accept)
+	}
+
+	// An explicit User-Agent option wins over the template header, the default is used only if neither is set
+	if c.UserAgent != "" {
+		httpReq.Header.Set("User-Agent", c.UserAgent)
+	} else if httpReq.Header.Get("User-Agent") == "" {
+		httpReq.Header.Set("User-Agent", DefaultUserAgent)
+	}
+
+	return httpReq, reqBytes, nil
+}
+
+// signRequest adds the timestamp and signature headers of the template's signing block, if it has one
+func (c *GenericClient) signRequest(template *templates.Template, httpReq *http.Request, reqBytes []byte) error {
+	if template.Signing == nil {
+		return nil
+	}
+	if c.SigningSecret == "" {
+		return fmt.Errorf("the template signs requests but no signing secret was provided (secret file entry '%s')", template.Signing.SecretKeyRef)
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	httpReq.Header.Set(template.Signing.TimestampHeaderName(), timestamp)
+	httpReq.Header.Set(template.Signing.HeaderName, template.Signing.Sign(c.SigningSecret, timestamp, httpReq.Method, httpReq.URL.RequestURI(), reqBytes))
+	return nil
+}
+
+// handleResponse reads the response, checks it for errors and extracts the content
+// The raw body is returned with errors so callers can report it
+func (c *GenericClient) handleResponse(template *templates.Template, resp *http.Response, start time.Time) (*Result, []byte, error) {
+	// Read the response body, decompressing it if needed; binary bodies are only decoded per Content-Encoding
+	bodyReader, err := decodeResponseBody(resp, template.Response.Type != templates.ResponseTypeBinary)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Successful event streams are read chunk by chunk, e.g. of a request with "stream": true
+	if isEventStream(resp) && resp.StatusCode >= 200 && resp.StatusCode <= 299 && !template.Response.IsRaw() {
+		result, body, err := c.readEventStream(template, bodyReader, resp.StatusCode)
+		c.logf("Response status: %s", resp.Status)
+		c.logf("Response time: %s", time.Since(start).Round(time.Millisecond))
+		c.logf("Response stream (%d bytes): %s", len(body), c.redact(string(body)))
+		return result, body, err
+	}
+
+	body, err := c.readResponseBody(bodyReader)
+	if err != nil {
+		return nil, body, err
+	}
+
+	c.logf("Response status: %s", resp.Status)
+	c.logf("Response time: %s", time.Since(start).Round(time.Millisecond))
+	c.logf("Response body (%d bytes): %s", len(body), c.redact(string(body)))
+
+	// Check for error response, preferring the provider's message at error_path
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		if response, err := parseResponseBody(body); err == nil {
+			if message, ok := errorMessageAt(response, template.Response.ErrorPath); ok {
+				return nil, body, fmt.Errorf("API request failed (status %d): %s", resp.StatusCode, message)
 			}
 		}
-	} else {
-		// Use path-based extraction directly
-		result, err = c.extractResponseContentByPath(body, template.Response.Path)
+		return nil, body, fmt.Errorf("API request failed (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	result, err := processResponseBody(template, body, resp.StatusCode)
+	return result, body, err
+}
+
+// ProcessResponse extracts the content from a saved response body the way Call does, without calling the API
+// It is used to tune the response settings of a template offline
+func ProcessResponse(template *templates.Template, body []byte) (*Result, error) {
+	if !template.Response.IsRaw() && isEventStreamBody(body) {
+		result, _, err := (&GenericClient{}).readEventStream(template, bytes.NewReader(body), http.StatusOK)
+		return result, err
+	}
+	if !template.Response.IsRaw() && !json.Valid(body) {
+		return nil, fmt.Errorf("response body is not valid JSON")
+	}
+	return processResponseBody(template, body, http.StatusOK)
+}
+
+// processResponseBody extracts the content from the body of a successful response
+func processResponseBody(template *templates.Template, body []byte, statusCode int) (*Result, error) {
+	// Requests other than POST (e.g. DELETE) may succeed without a response body
+	if template.Request.Method != http.MethodPost && len(bytes.TrimSpace(body)) == 0 {
+		return &Result{StatusCode: statusCode, Body: body}, nil
+	}
+
+	switch template.Response.Type {
+	case templates.ResponseTypeBinary:
+		// Binary bodies (audio, images) are passed through untouched
+		return &Result{Content: string(body), StatusCode: statusCode, Body: body}, nil
+	case templates.ResponseTypeText:
+		return processTextBody(template, body, statusCode)
+	}
+
+	// Parse the response once for extraction and later inspection (e.g. usage)
+	response, err := parseResponseBody(body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Some providers report errors in the body of a successful response
+	if message, ok := errorMessageAt(response, template.Response.ErrorPath); ok {
+		return nil, fmt.Errorf("API returned an error: %s", message)
+	}
+
+	result, err := extractContent(response, template.Response)
+	if err != nil && template.Request.Method != http.MethodPost && template.Response.Path == "" && len(template.Response.Paths) == 0 && template.Response.Mode == "" && template.Response.Expr == "" {
+		// Requests other than POST are not completions, without a response path they return the whole response
+		result, err = string(body), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply response transforms in order
+	result, err = transform.Apply(result, template.Response.Transform)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate structured output against the response schema
+	if err := template.ValidateResponseContent(result); err != nil {
+		return nil, err
+	}
+
+	processed := &Result{Content: result, Response: response, StatusCode: statusCode, Body: body}
+	if usage, ok := ParseUsage(response); ok {
+		processed.Usage = &usage
+	}
+	return processed, nil
+}
+
+// processTextBody uses a plain text or markdown body as the content, ignoring the response path
+func processTextBody(template *templates.Template, body []byte, statusCode int) (*Result, error) {
+	result, err := transform.Apply(string(body), template.Response.Transform)
+	if err != nil {
+		return nil, err
+	}
+	if err := template.ValidateResponseContent(result); err != nil {
+		return nil, err
+	}
+	return &Result{Content: result, StatusCode: statusCode, Body: body}, nil
+}
+
+// readResponseBody reads the response body, enforcing the size limit if one is set
+func (c *GenericClient) readResponseBody(r io.Reader) ([]byte, error) {
+	if c.MaxResponseBytes <= 0 {
+		body, err := io.ReadAll(r)
 		if err != nil {
-			// Preserve the detailed error from extractResponseContentByPath
-			return "", err
+			return nil, fmt.Errorf("failed to read response body: %w", err)
 		}
+		return body, nil
 	}
 
-	return result, nil
+	// Read one byte past the limit to detect oversized responses without buffering them
+	body, err := io.ReadAll(io.LimitReader(r, c.MaxResponseBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if int64(len(body)) > c.MaxResponseBytes {
+		return nil, fmt.Errorf("response body exceeds the limit of %d bytes", c.MaxResponseBytes)
+	}
+	return body, nil
 }
 
-// autoDetectResponseContent tries to automatically detect the response format
-func (c *GenericClient) autoDetectResponseContent(body []byte, preferredResponseField string) (string, error) {
-	var response map[string]interface{}
-	if err := json.Unmarshal(body, &response); err != nil {
-		return "", fmt.Errorf("failed to parse response JSON: %w", err)
+// logRequest logs the resolved request with the API key redacted
+func (c *GenericClient) logRequest(req *http.Request, bodySize int) {
+	if c.Logger == nil {
+		return
+	}
+
+	c.logf("Request: %s %s", req.Method, c.redact(req.URL.String()))
+
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		names = append(names, name)
 	}
+	sort.Strings(names)
+	for _, name := range names {
+		c.logf("Request header: %s: %s", name, c.redact(strings.Join(req.Header.Values(name), ", ")))
+	}
+
+	c.logf("Request body size: %d bytes", bodySize)
+}
 
+// autoDetectResponseContent tries to automatically detect the response format
+func autoDetectResponseContent(response interface{}, preferredResponseField string) (string, error) {
 	// If a specific response field is requested, try that first
 	if preferredResponseField != "" {
-		if content, ok := response[preferredResponseField]; ok {
-			if strContent, ok := content.(string); ok {
-				return strContent, nil
-			}
+		if content, ok := navigateToField(response, preferredResponseField).(string); ok {
+			return content, nil
 		}
 	}
 
@@ -117,34 +530,291 @@ func (c *GenericClient) autoDetectResponseContent(body []byte, preferredResponse
 	return content, nil
 }
 
+// extractContent extracts the content using auto-detection if enabled, otherwise the response path
+// When auto-detection fails, the response path is used if one is set
+func extractContent(response interface{}, responseConfig templates.ResponseConfig) (string, error) {
+	if responseConfig.Expr != "" {
+		return responseConfig.EvaluateExpr(response)
+	}
+	if len(responseConfig.Paths) > 0 {
+		return extractNamedFields(response, responseConfig.Paths)
+	}
+	if responseConfig.Mode == templates.ResponseModeToolCall {
+		return extractToolCalls(response)
+	}
+	if responseConfig.Mode == templates.ResponseModeFiles {
+		return extractFiles(response, responseConfig.Path)
+	}
+	if !responseConfig.AutoDetectEnabled() {
+		return extractResponseContentByPath(response, responseConfig.Path)
+	}
+
+	result, err := autoDetectResponseContent(response, responseConfig.ResponseFieldName)
+	if err == nil {
+		return result, nil
+	}
+	if responseConfig.Path != "" {
+		// Fall back to path-based extraction, preserving its detailed error
+		return extractResponseContentByPath(response, responseConfig.Path)
+	}
+
+	prettyResponse, _ := formatResponseStructure(response)
+	return "", fmt.Errorf("%w, set response.path in the template. API response structure: %s", err, prettyResponse)
+}
+
+// CheckResponseContent extracts the content from a sample response body the way Call does
+// It is used to test templates against a sample response without calling the API
+func CheckResponseContent(body []byte, responseConfig templates.ResponseConfig) (string, error) {
+	if !responseConfig.AutoDetectEnabled() && len(responseConfig.Paths) == 0 && responseConfig.Mode == "" && responseConfig.Expr == "" {
+		return CheckResponsePath(body, responseConfig.Path)
+	}
+
+	response, err := parseResponseBody(body)
+	if err != nil {
+		return "", err
+	}
+	return extractContent(response, responseConfig)
+}
+
 // extractResponseContentByPath extracts content from the response using a dot-notation path
 // This is the original path-based extraction logic
-func (c *GenericClient) extractResponseContentByPath(body []byte, responsePath string) (string, error) {
-	if responsePath == "" {
-		return "", fmt.Errorf("response path is required for extraction")
+func extractResponseContentByPath(response interface{}, responsePath string) (string, error) {
+	current, err := lookupResponsePath(response, responsePath)
+	if err != nil {
+		return "", err
 	}
 
-	var response map[string]interface{}
-	if err := json.Unmarshal(body, &response); err != nil {
-		return "", fmt.Errorf("failed to parse response JSON: %w", err)
+	// Convert the final result to string
+	if str, ok := current.(string); ok {
+		return str, nil
 	}
 
-	// Navigate through the response path
-	parts := strings.Split(responsePath, ".")
-	current := interface{}(response)
-	var pathSoFar string
+	// Encode other values (objects, arrays, numbers, booleans) as JSON
+	data, err := json.Marshal(current)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode value at response path '%s' as JSON: %w", responsePath, err)
+	}
+	return string(data), nil
+}
+
+// extractNamedFields extracts the value at each named path and encodes them as a JSON object
+// String values stay strings, other values keep their JSON type
+func extractNamedFields(response interface{}, paths map[string]string) (string, error) {
+	fields := make(map[string]interface{}, len(paths))
+	for name, path := range paths {
+		value, err := lookupResponsePath(response, path)
+		if err != nil {
+			return "", fmt.Errorf("response.paths '%s': %w", name, err)
+		}
+		fields[name] = value
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode response.paths values as JSON: %w", err)
+	}
+	return string(data), nil
+}
+
+// toolCallsPath is the path of the tool calls in an OpenAI-style chat completion
+const toolCallsPath = "choices[0].message.tool_calls"
+
+// toolCall is one tool call returned by the tool_call response mode
+type toolCall struct {
+	ID        string      `json:"id,omitempty"`
+	Name      string      `json:"name"`
+	Arguments interface{} `json:"arguments"`
+}
+
+// extractToolCalls returns the tool calls of an OpenAI-style chat completion as a JSON array
+// Arguments are included as JSON values when the model returned valid JSON, otherwise as strings
+func extractToolCalls(response interface{}) (string, error) {
+	value, err := lookupResponsePath(response, toolCallsPath)
+	if err != nil {
+		return "", fmt.Errorf("response has no tool calls: %w", err)
+	}
+	calls, ok := value.([]interface{})
+	if !ok || len(calls) == 0 {
+		return "", fmt.Errorf("response has no tool calls at '%s'", toolCallsPath)
+	}
+
+	result := make([]toolCall, 0, len(calls))
+	for _, call := range calls {
+		entry, _ := call.(map[string]interface{})
+		function, _ := entry["function"].(map[string]interface{})
+		id, _ := entry["id"].(string)
+		name, _ := function["name"].(string)
+
+		arguments := function["arguments"]
+		if text, ok := arguments.(string); ok && json.Valid([]byte(text)) {
+			arguments = json.RawMessage(text)
+		}
+		result = append(result, toolCall{ID: id, Name: name, Arguments: arguments})
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode tool calls as JSON: %w", err)
+	}
+	return string(data), nil
+}
+
+// extractFiles returns the strings of the array at a response path as a JSON array, e.g. base64 encoded images
+// A wildcard path (data[*].b64_json) collects the value of each element instead of joining them
+func extractFiles(response interface{}, responsePath string) (string, error) {
+	values, err := lookupResponseList(response, responsePath)
+	if err != nil {
+		return "", err
+	}
+
+	files := make([]string, 0, len(values))
+	for i, value := range values {
+		str, ok := value.(string)
+		if !ok {
+			return "", fmt.Errorf("file %d at response path '%s' is %T, expected a base64 string", i+1, responsePath, value)
+		}
+		files = append(files, str)
+	}
+	if len(files) == 0 {
+		return "", fmt.Errorf("no files at response path '%s'", responsePath)
+	}
+
+	data, err := json.Marshal(files)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode files as JSON: %w", err)
+	}
+	return string(data), nil
+}
 
+// lookupResponseList returns the elements of the array at a response path
+// For a path with a wildcard the rest of the path is resolved for each element, skipping elements where it does not resolve
+func lookupResponseList(response interface{}, responsePath string) ([]interface{}, error) {
+	parts := strings.Split(responsePath, ".")
 	for i, part := range parts {
-		pathSoFar = strings.Join(parts[:i+1], ".")
+		if !strings.HasSuffix(part, "[*]") {
+			continue
+		}
+
+		// Resolve the array the wildcard applies to, then the rest of the path per element
+		arrayParts := append(append([]string(nil), parts[:i]...), strings.TrimSuffix(part, "[*]"))
+		if arrayParts[i] == "" {
+			arrayParts = arrayParts[:i]
+		}
+		current, err := resolvePath(response, response, arrayParts, 0)
+		if err != nil {
+			return nil, err
+		}
+		arr, ok := current.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected array but got %T at '%s' in response path '%s'", current, strings.Join(arrayParts, "."), responsePath)
+		}
+
+		var values []interface{}
+		for _, element := range arr {
+			if value, err := resolvePath(response, element, parts, i+1); err == nil {
+				values = append(values, value)
+			}
+		}
+		return values, nil
+	}
+
+	value, err := lookupResponsePath(response, responsePath)
+	if err != nil {
+		return nil, err
+	}
+	arr, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected array but got %T at response path '%s'", value, responsePath)
+	}
+	return arr, nil
+}
+
+// CheckResponsePath verifies that a dot-notation path resolves to a string in the response body
+// It is used to test templates against a sample response without calling the API
+func CheckResponsePath(body []byte, responsePath string) (string, error) {
+	response, err := parseResponseBody(body)
+	if err != nil {
+		return "", err
+	}
+
+	current, err := lookupResponsePath(response, responsePath)
+	if err != nil {
+		return "", err
+	}
+
+	str, ok := current.(string)
+	if !ok {
+		return "", fmt.Errorf("response path '%s' resolves to %T, expected a string", responsePath, current)
+	}
+	return str, nil
+}
+
+// errorMessageAt returns the error message at errorPath if it resolves to a non-empty value
+func errorMessageAt(response interface{}, errorPath string) (string, bool) {
+	if errorPath == "" {
+		return "", false
+	}
+
+	value, err := lookupResponsePath(response, errorPath)
+	if err != nil {
+		return "", false
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return "", false
+	case string:
+		return v, v != ""
+	case bool:
+		return "true", v
+	case map[string]interface{}:
+		if len(v) == 0 {
+			return "", false
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			return "", false
+		}
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value), true
+	}
+	return string(data), true
+}
+
+// parseResponseBody parses a JSON response body, an object or a top-level array such as [{"generated_text": "..."}]
+func parseResponseBody(body []byte) (interface{}, error) {
+	var response interface{}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse response JSON: %w", err)
+	}
+	return response, nil
+}
+
+// lookupResponsePath returns the value at a dot-notation path in the parsed response
+// Array indices may be negative to count from the end (choices[-1]), and a wildcard
+// (choices[*]) resolves the rest of the path for every element and joins the values with newlines.
+// A path starting with an index ([0].text) indexes a top-level array response
+func lookupResponsePath(response interface{}, responsePath string) (interface{}, error) {
+	if responsePath == "" {
+		return nil, fmt.Errorf("response path is required for extraction")
+	}
+
+	return resolvePath(response, response, strings.Split(responsePath, "."), 0)
+}
+
+// resolvePath navigates parts[start:] from current, the value already resolved for parts[:start]
+func resolvePath(response interface{}, current interface{}, parts []string, start int) (interface{}, error) {
+	for i := start; i < len(parts); i++ {
+		part := parts[i]
+		pathSoFar := strings.Join(parts[:i+1], ".")
 
 		// Handle array indices like "choices[0]"
 		if strings.Contains(part, "[") && strings.Contains(part, "]") {
 			arrayName := part[:strings.Index(part, "[")]
 			indexStr := part[strings.Index(part, "[")+1 : strings.Index(part, "]")]
-			index, err := strconv.Atoi(indexStr)
-			if err != nil {
-				return "", fmt.Errorf("invalid array index '%s' in response path", indexStr)
-			}
 
 			// Navigate to the array
 			if arrayName != "" {
@@ -152,48 +822,84 @@ func (c *GenericClient) extractResponseContentByPath(body []byte, responsePath s
 				if current == nil {
 					// Show error with response structure for better debugging
 					prettyResponse, _ := formatResponseStructure(response)
-					return "", fmt.Errorf("field '%s' not found in response path '%s'. API response structure: %s",
+					return nil, fmt.Errorf("field '%s' not found in response path '%s'. API response structure: %s",
 						arrayName, pathSoFar, prettyResponse)
 				}
 			}
 
-			// Get the array element
-			if arr, ok := current.([]interface{}); ok {
-				if index >= len(arr) {
-					prettyResponse, _ := formatResponseStructure(response)
-					return "", fmt.Errorf("array index %d out of bounds in response path '%s' (array length: %d). API response structure: %s",
-						index, pathSoFar, len(arr), prettyResponse)
-				}
-				current = arr[index]
-			} else {
+			arr, ok := current.([]interface{})
+			if !ok {
 				prettyResponse, _ := formatResponseStructure(response)
-				return "", fmt.Errorf("expected array but got %T for field '%s' in path '%s'. API response structure: %s",
+				return nil, fmt.Errorf("expected array but got %T for field '%s' in path '%s'. API response structure: %s",
 					current, arrayName, pathSoFar, prettyResponse)
 			}
+
+			if indexStr == "*" {
+				return resolveWildcard(response, arr, parts, i+1)
+			}
+
+			index, err := strconv.Atoi(indexStr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid array index '%s' in response path", indexStr)
+			}
+
+			// Negative indices count from the end of the array
+			position := index
+			if position < 0 {
+				position += len(arr)
+			}
+			if position < 0 || position >= len(arr) {
+				prettyResponse, _ := formatResponseStructure(response)
+				return nil, fmt.Errorf("array index %d out of bounds in response path '%s' (array length: %d). API response structure: %s",
+					index, pathSoFar, len(arr), prettyResponse)
+			}
+			current = arr[position]
 		} else {
 			// Regular field navigation
 			current = navigateToField(current, part)
 			if current == nil {
 				// Show error with response structure for better debugging
 				prettyResponse, _ := formatResponseStructure(response)
-				return "", fmt.Errorf("field '%s' not found in response path '%s'. API response structure: %s",
+				return nil, fmt.Errorf("field '%s' not found in response path '%s'. API response structure: %s",
 					part, pathSoFar, prettyResponse)
 			}
 		}
 	}
 
-	// Convert the final result to string
-	if str, ok := current.(string); ok {
-		return str, nil
+	return current, nil
+}
+
+// resolveWildcard resolves parts[next:] for every array element and joins the values with newlines
+// Elements where the rest of the path does not resolve are skipped; non-string values are encoded as JSON
+func resolveWildcard(response interface{}, arr []interface{}, parts []string, next int) (interface{}, error) {
+	var values []string
+	for _, element := range arr {
+		value, err := resolvePath(response, element, parts, next)
+		if err != nil {
+			continue
+		}
+		if str, ok := value.(string); ok {
+			values = append(values, str)
+			continue
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			continue
+		}
+		values = append(values, string(data))
 	}
 
-	// If it's not a string, try to convert it
-	return fmt.Sprintf("%v", current), nil
+	if len(values) == 0 {
+		prettyResponse, _ := formatResponseStructure(response)
+		return nil, fmt.Errorf("no values matched the wildcard in response path '%s' (array length: %d). API response structure: %s",
+			strings.Join(parts, "."), len(arr), prettyResponse)
+	}
+	return strings.Join(values, "\n"), nil
 }
 
 // formatResponseStructure returns a formatted string representation of the response structure
 // It's used for debugging when a path can't be found
-func formatResponseStructure(response map[string]interface{}) (string, error) {
+func formatResponseStructure(response interface{}) (string, error) {
 	// Pretty-print the response structure with indent
 	prettyJSON, err := json.MarshalIndent(response, "", "  ")
 	if err != nil {
@@ -208,74 +914,64 @@ func formatResponseStructure(response map[string]interface{}) (string, error) {
 	return string(prettyJSON), nil
 }
 
-// detectResponseFormat attempts to identify and extract content from common LLM response formats
-// Handles various formats from different providers:
-// - Ollama (legacy and newer versions)
-// - OpenAI API (chat completions and standard completions)
-// - Anthropic
-// - Cohere
-// - Claude
-// Returns the extracted content string and a boolean success indicator
-func detectResponseFormat(response map[string]interface{}) (string, bool) {
-	// Ollama format (new) - direct "response" field
-	// {"model":"qwen2.5vl","created_at":"...","response":"Hello!...","done":true}
-	if response, ok := response["response"]; ok {
-		if strResponse, ok := response.(string); ok {
-			return strResponse, true
-		}
-	}
+// ResponseFormat describes a response format recognized by auto-detection
+type ResponseFormat struct {
+	// Name describes the provider API producing the format
+	Name string
 
-	// OpenAI format - choices[0].message.content or choices[0].text
-	if choices, ok := response["choices"].([]interface{}); ok && len(choices) > 0 {
-		choice := choices[0]
-		if choiceMap, ok := choice.(map[string]interface{}); ok {
-			// ChatCompletions format (choices[0].message.content)
-			if message, ok := choiceMap["message"].(map[string]interface{}); ok {
-				if content, ok := message["content"].(string); ok {
-					return content, true
-				}
-			}
-			// Completions format (choices[0].text)
-			if text, ok := choiceMap["text"].(string); ok {
-				return text, true
-			}
-		}
-	}
+	// Path is the dot-notation path of the content, used if it holds a string
+	Path string
+}
 
-	// Anthropic format - content or completion
-	if content, ok := response["content"].(string); ok {
-		return content, true
-	}
-	if completion, ok := response["completion"].(string); ok {
-		return completion, true
-	}
+// ResponseFormats lists the formats recognized by auto-detection, in the order they are checked
+var ResponseFormats = []ResponseFormat{
+	// {"model":"qwen2.5vl","created_at":"...","response":"Hello!...","done":true}
+	{Name: "Ollama generate", Path: "response"},
+	{Name: "OpenAI chat completions (and compatible APIs)", Path: "choices[0].message.content"},
+	// Tool calls have a null content, the first call's arguments are returned
+	{Name: "OpenAI tool calls", Path: "choices[0].message.tool_calls[0].function.arguments"},
+	{Name: "OpenAI completions", Path: "choices[0].text"},
+	{Name: "Anthropic legacy / older Ollama", Path: "content"},
+	{Name: "Anthropic text completions", Path: "completion"},
+	{Name: "Cohere generate", Path: "generations[0].text"},
+	{Name: "Anthropic Claude messages", Path: "content[0].text"},
+	// [{"generated_text":"Hello!..."}]
+	{Name: "Hugging Face text generation", Path: "[0].generated_text"},
+}
 
-	// Cohere format - generations[0].text
-	if generations, ok := response["generations"].([]interface{}); ok && len(generations) > 0 {
-		generation := generations[0]
-		if genMap, ok := generation.(map[string]interface{}); ok {
-			if text, ok := genMap["text"].(string); ok {
-				return text, true
-			}
+// detectResponseFormat returns the content of the first recognized response format
+func detectResponseFormat(response interface{}) (string, bool) {
+	for _, format := range ResponseFormats {
+		if content, ok := stringAtPath(response, format.Path); ok {
+			return content, true
 		}
 	}
 
-	// Ollama format (older version) - content
-	if content, ok := response["content"].(string); ok {
-		return content, true
-	}
+	// No recognized format
+	return "", false
+}
 
-	// Claude format - content[0].text
-	if content, ok := response["content"].([]interface{}); ok && len(content) > 0 {
-		if contentMap, ok := content[0].(map[string]interface{}); ok {
-			if text, ok := contentMap["text"].(string); ok {
-				return text, true
+// stringAtPath returns the string at a simple dot-notation path (fields and non-negative indices)
+// Unlike lookupResponsePath it builds no error messages, so it is cheap to call for detection
+func stringAtPath(response interface{}, path string) (string, bool) {
+	current := response
+	for _, part := range strings.Split(path, ".") {
+		field, indexStr, hasIndex := strings.Cut(part, "[")
+		if field != "" {
+			current = navigateToField(current, field)
+		}
+		if hasIndex {
+			index, err := strconv.Atoi(strings.TrimSuffix(indexStr, "]"))
+			arr, ok := current.([]interface{})
+			if err != nil || !ok || index < 0 || index >= len(arr) {
+				return "", false
 			}
+			current = arr[index]
 		}
 	}
 
-	// No recognized format
-	return "", false
+	content, ok := current.(string)
+	return content, ok
 }
 
 // navigateToField navigates to a specific field in a map or struct
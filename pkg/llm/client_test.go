@@ -0,0 +1,395 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nodewee/llm-caller/pkg/templates"
+)
+
+// recordedRequest is what a test server received
+type recordedRequest struct {
+	Method string
+	Header http.Header
+	Body   string
+}
+
+// recordingServer answers every request with status and body and records the last request
+func recordingServer(t *testing.T, status int, body string) (*httptest.Server, *recordedRequest) {
+	t.Helper()
+	recorded := &recordedRequest{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		*recorded = recordedRequest{Method: r.Method, Header: r.Header.Clone(), Body: string(data)}
+		if body != "" {
+			w.Header().Set("Content-Type", "application/json")
+		}
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(server.Close)
+	return server, recorded
+}
+
+// newTestClient returns a client without proxy or rate limit settings
+func newTestClient(t *testing.T, opts ClientOptions) *GenericClient {
+	t.Helper()
+	client, err := NewGenericClient("sk-test", opts)
+	if err != nil {
+		t.Fatalf("NewGenericClient: %v", err)
+	}
+	return client
+}
+
+func TestCallMethods(t *testing.T) {
+	tests := []struct {
+		method   string
+		body     string
+		status   int
+		response string
+		wantBody bool
+	}{
+		{method: "GET", status: http.StatusOK, response: `{"data":[]}`},
+		{method: "GET", body: `{"ignored":true}`, status: http.StatusOK, response: `{"data":[]}`},
+		{method: "PUT", body: `{"name":"a"}`, status: http.StatusOK, response: `{"id":"1"}`, wantBody: true},
+		{method: "PATCH", body: `{"name":"b"}`, status: http.StatusOK, response: `{"id":"1"}`, wantBody: true},
+		{method: "DELETE", status: http.StatusNoContent},
+		{method: "DELETE", body: `{"ids":["1"]}`, status: http.StatusNoContent, wantBody: true},
+	}
+
+	for _, tt := range tests {
+		name := tt.method + " without body"
+		if tt.body != "" {
+			name = tt.method + " with body"
+		}
+		t.Run(name, func(t *testing.T) {
+			server, recorded := recordingServer(t, tt.status, tt.response)
+			templateJSON := fmt.Sprintf(`{"provider": "test", "request": {"url": %q, "method": %q}}`, server.URL, tt.method)
+			if tt.body != "" {
+				templateJSON = fmt.Sprintf(`{"provider": "test", "request": {"url": %q, "method": %q, "body": %s}}`, server.URL, tt.method, tt.body)
+			}
+			template, err := templates.LoadTemplateFromJSON(templateJSON)
+			if err != nil {
+				t.Fatalf("LoadTemplateFromJSON: %v", err)
+			}
+
+			result, err := newTestClient(t, ClientOptions{}).Call(template)
+			if err != nil {
+				t.Fatalf("Call: %v", err)
+			}
+			if result.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", result.StatusCode, tt.status)
+			}
+			if recorded.Method != tt.method {
+				t.Errorf("method = %s, want %s", recorded.Method, tt.method)
+			}
+
+			contentType := recorded.Header.Get("Content-Type")
+			if tt.wantBody {
+				if recorded.Body != tt.body {
+					t.Errorf("body = %q, want %q", recorded.Body, tt.body)
+				}
+				if contentType != "application/json" {
+					t.Errorf("Content-Type = %q, want application/json", contentType)
+				}
+			} else {
+				if recorded.Body != "" {
+					t.Errorf("bodyless request sent body %q", recorded.Body)
+				}
+				if contentType != "" {
+					t.Errorf("bodyless request sent Content-Type %q", contentType)
+				}
+			}
+			if tt.response == "" && result.Content != "" {
+				t.Errorf("content of an empty response = %q, want empty", result.Content)
+			}
+		})
+	}
+}
+
+func TestBodyRequiredForPutAndPatch(t *testing.T) {
+	for _, method := range []string{"PUT", "PATCH"} {
+		templateJSON := fmt.Sprintf(`{"provider": "test", "request": {"url": "http://localhost", "method": %q}}`, method)
+		if _, err := templates.LoadTemplateFromJSON(templateJSON); err == nil {
+			t.Errorf("%s template without a body was accepted", method)
+		}
+	}
+}
+
+// mustParse parses a JSON response fixture
+func mustParse(t *testing.T, body string) interface{} {
+	t.Helper()
+	response, err := parseResponseBody([]byte(body))
+	if err != nil {
+		t.Fatalf("parseResponseBody: %v", err)
+	}
+	return response
+}
+
+func TestLookupResponsePath(t *testing.T) {
+	response := mustParse(t, `{
+		"choices": [
+			{"message": {"content": "first"}},
+			{"message": {"content": "second"}},
+			{"message": {"content": "last"}}
+		],
+		"matrix": [[1, 2], [3]],
+		"groups": [
+			{"items": [{"name": "a"}, {"name": "b"}]},
+			{"items": []},
+			{"items": [{"name": "c"}]}
+		]
+	}`)
+
+	tests := []struct {
+		path    string
+		want    interface{}
+		wantErr bool
+	}{
+		{path: "choices[0].message.content", want: "first"},
+		{path: "choices[-1].message.content", want: "last"},
+		{path: "choices[-3].message.content", want: "first"},
+		{path: "choices[-4].message.content", wantErr: true},
+		{path: "choices[3].message.content", wantErr: true},
+		{path: "choices[*].message.content", want: "first\nsecond\nlast"},
+		{path: "choices[*].message", want: `{"content":"first"}` + "\n" + `{"content":"second"}` + "\n" + `{"content":"last"}`},
+		{path: "matrix[*]", want: "[1,2]\n[3]"},
+		{path: "groups[*].items[*].name", want: "a\nb\nc"},
+		{path: "choices[*].missing", wantErr: true},
+		{path: "choices[x]", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := lookupResponsePath(response, tt.path)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("lookupResponsePath(%q) = %v, want an error", tt.path, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("lookupResponsePath(%q): %v", tt.path, err)
+			}
+			if got != tt.want {
+				t.Errorf("lookupResponsePath(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractContentAutoDetect(t *testing.T) {
+	enabled := true
+	response := mustParse(t, `{"answer": "preferred", "choices": [{"message": {"content": "detected"}}]}`)
+
+	tests := []struct {
+		name   string
+		config templates.ResponseConfig
+		want   string
+	}{
+		{name: "detected format", config: templates.ResponseConfig{AutoDetect: &enabled}, want: "detected"},
+		{name: "response_field first", config: templates.ResponseConfig{AutoDetect: &enabled, ResponseFieldName: "answer"}, want: "preferred"},
+		{name: "missing response_field", config: templates.ResponseConfig{AutoDetect: &enabled, ResponseFieldName: "other"}, want: "detected"},
+		{name: "path fallback", config: templates.ResponseConfig{AutoDetect: &enabled, Path: "answer"}, want: "detected"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := extractContent(response, tt.config)
+			if err != nil {
+				t.Fatalf("extractContent: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("extractContent = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Without a recognized format the path is used
+	unknown := mustParse(t, `{"result": {"text": "from path"}}`)
+	got, err := extractContent(unknown, templates.ResponseConfig{AutoDetect: &enabled, Path: "result.text"})
+	if err != nil || got != "from path" {
+		t.Errorf("extractContent with path fallback = %q, %v, want %q", got, err, "from path")
+	}
+}
+
+func TestGeminiResponseWithoutPath(t *testing.T) {
+	server, _ := recordingServer(t, http.StatusOK, `{"candidates": [{"content": {"parts": [{"text": "Hello from Gemini"}]}}]}`)
+	template, err := templates.LoadTemplateFromJSON(fmt.Sprintf(`{"provider": "gemini", "request": {"url": %q, "body": {"contents": []}}}`, server.URL))
+	if err != nil {
+		t.Fatalf("LoadTemplateFromJSON: %v", err)
+	}
+	result, err := newTestClient(t, ClientOptions{}).Call(template)
+	if err != nil {
+		t.Fatalf("Call: %v", err)
+	}
+	if result.Content != "Hello from Gemini" {
+		t.Errorf("Content = %q, want %q", result.Content, "Hello from Gemini")
+	}
+}
+
+func TestExtractContentEncodesNonStringValues(t *testing.T) {
+	response := mustParse(t, `{
+		"choices": [{"index": 0, "message": {"content": "Hi"}}],
+		"usage": {"prompt_tokens": 3, "completion_tokens": 5},
+		"done": true
+	}`)
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "usage", want: `{"completion_tokens":5,"prompt_tokens":3}`},
+		{path: "choices", want: `[{"index":0,"message":{"content":"Hi"}}]`},
+		{path: "usage.prompt_tokens", want: "3"},
+		{path: "done", want: "true"},
+		{path: "choices[0].message.content", want: "Hi"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := extractContent(response, templates.ResponseConfig{Path: tt.path})
+			if err != nil {
+				t.Fatalf("extractContent: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("extractContent(%q) = %s, want %s", tt.path, got, tt.want)
+			}
+		})
+	}
+
+	// Auto-detection only accepts string content
+	if content, ok := stringAtPath(response, "usage"); ok {
+		t.Errorf("stringAtPath(usage) = %q, want no string", content)
+	}
+}
+
+func TestUserAgentPrecedence(t *testing.T) {
+	tests := []struct {
+		name           string
+		templateHeader string
+		option         string
+		want           string
+	}{
+		{name: "default", want: DefaultUserAgent},
+		{name: "template header", templateHeader: "my-app/1.0", want: "my-app/1.0"},
+		{name: "option", option: "cli/2.0", want: "cli/2.0"},
+		{name: "option over template header", templateHeader: "my-app/1.0", option: "cli/2.0", want: "cli/2.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, recorded := recordingServer(t, http.StatusOK, `{"response": "ok"}`)
+			headers := `{}`
+			if tt.templateHeader != "" {
+				headers = fmt.Sprintf(`{"User-Agent": %q}`, tt.templateHeader)
+			}
+			template, err := templates.LoadTemplateFromJSON(fmt.Sprintf(`{"provider": "test", "request": {"url": %q, "headers": %s, "body": {}}}`, server.URL, headers))
+			if err != nil {
+				t.Fatalf("LoadTemplateFromJSON: %v", err)
+			}
+
+			if _, err := newTestClient(t, ClientOptions{UserAgent: tt.option}).Call(template); err != nil {
+				t.Fatalf("Call: %v", err)
+			}
+			if got := recorded.Header.Get("User-Agent"); got != tt.want {
+				t.Errorf("User-Agent = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTopLevelArrayResponse(t *testing.T) {
+	body := `[{"generated_text": "first"}, {"generated_text": "second"}]`
+
+	tests := []struct {
+		name   string
+		config templates.ResponseConfig
+		want   string
+	}{
+		{name: "auto-detect", config: templates.ResponseConfig{}, want: "first"},
+		{name: "index", config: templates.ResponseConfig{Path: "[1].generated_text"}, want: "second"},
+		{name: "negative index", config: templates.ResponseConfig{Path: "[-1].generated_text"}, want: "second"},
+		{name: "wildcard", config: templates.ResponseConfig{Path: "[*].generated_text"}, want: "first\nsecond"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CheckResponseContent([]byte(body), tt.config)
+			if err != nil {
+				t.Fatalf("CheckResponseContent: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("content = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// The whole call path keeps the parsed array as the response
+	server, _ := recordingServer(t, http.StatusOK, body)
+	template, err := templates.LoadTemplateFromJSON(fmt.Sprintf(`{"provider": "huggingface", "request": {"url": %q, "body": {"inputs": "hi"}}}`, server.URL))
+	if err != nil {
+		t.Fatalf("LoadTemplateFromJSON: %v", err)
+	}
+	result, err := newTestClient(t, ClientOptions{}).Call(template)
+	if err != nil {
+		t.Fatalf("Call: %v", err)
+	}
+	if result.Content != "first" {
+		t.Errorf("Content = %q, want %q", result.Content, "first")
+	}
+	if _, ok := result.Response.([]interface{}); !ok {
+		t.Errorf("Response is %T, want []interface{}", result.Response)
+	}
+}
+
+func TestPaginatedCallSumsUsage(t *testing.T) {
+	pages := map[string]string{
+		"":   `{"choices": [{"message": {"content": "one"}}], "usage": {"prompt_tokens": 10, "completion_tokens": 3, "total_tokens": 13}, "next": "p2"}`,
+		"p2": `{"choices": [{"message": {"content": "two"}}], "usage": {"prompt_tokens": 12, "completion_tokens": 4, "total_tokens": 16}, "next": "p3"}`,
+		"p3": `{"choices": [{"message": {"content": "three"}}]}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		cursor, _ := body["cursor"].(string)
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, pages[cursor])
+	}))
+	defer server.Close()
+
+	template, err := templates.LoadTemplateFromJSON(fmt.Sprintf(`{
+		"provider": "openai",
+		"request": {"url": %q, "body": {"model": "m"}},
+		"response": {"path": "choices[0].message.content"},
+		"pagination": {"next_path": "next", "cursor_param": "cursor"}
+	}`, server.URL))
+	if err != nil {
+		t.Fatalf("LoadTemplateFromJSON: %v", err)
+	}
+	result, err := newTestClient(t, ClientOptions{}).Call(template)
+	if err != nil {
+		t.Fatalf("Call: %v", err)
+	}
+	if result.Content != "one\ntwo\nthree" {
+		t.Errorf("Content = %q, want the pages joined", result.Content)
+	}
+	want := Usage{PromptTokens: 22, CompletionTokens: 7, TotalTokens: 29}
+	if result.Usage == nil || *result.Usage != want {
+		t.Errorf("Usage = %+v, want %+v", result.Usage, want)
+	}
+}
+
+func TestResultUsage(t *testing.T) {
+	server, _ := recordingServer(t, http.StatusOK, `{"choices": [{"message": {"content": "Hi"}}]}`)
+	template, err := templates.LoadTemplateFromJSON(fmt.Sprintf(`{"provider": "openai", "request": {"url": %q, "body": {}}}`, server.URL))
+	if err != nil {
+		t.Fatalf("LoadTemplateFromJSON: %v", err)
+	}
+	result, err := newTestClient(t, ClientOptions{}).Call(template)
+	if err != nil {
+		t.Fatalf("Call: %v", err)
+	}
+	if result.Usage != nil {
+		t.Errorf("Usage = %+v, want nil when the API reports none", result.Usage)
+	}
+}
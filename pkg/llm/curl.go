@@ -0,0 +1,89 @@
+package llm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nodewee/llm-caller/pkg/templates"
+	"github.com/nodewee/llm-caller/pkg/utils"
+)
+
+// CurlCommand returns a curl command line sending the same request as Call, for a POSIX shell
+// The API key and signing secret are redacted unless includeSecrets is set. Signed requests carry
+// a signature for the current time, so the command only works while the API accepts that timestamp
+func (c *GenericClient) CurlCommand(template *templates.Template, includeSecrets bool) (string, error) {
+	httpReq, reqBytes, err := c.buildRequest(template)
+	if err != nil {
+		return "", err
+	}
+	if err := c.signRequest(template, httpReq, reqBytes); err != nil {
+		return "", err
+	}
+
+	multipartBody := template.Request.BodyType == templates.BodyTypeMultipart && !template.Request.IsBodyless()
+	args := []string{"curl -X " + httpReq.Method + " " + utils.ShellQuote(httpReq.URL.String())}
+
+	names := make([]string, 0, len(httpReq.Header))
+	for name := range httpReq.Header {
+		// curl generates the multipart Content-Type with its own boundary
+		if multipartBody && name == "Content-Type" {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range httpReq.Header.Values(name) {
+			args = append(args, "-H "+utils.ShellQuote(name+": "+value))
+		}
+	}
+
+	switch {
+	case multipartBody:
+		formArgs, err := curlFormArgs(template.Request.Body)
+		if err != nil {
+			return "", err
+		}
+		args = append(args, formArgs...)
+	case reqBytes != nil:
+		args = append(args, "--data-raw "+utils.ShellQuote(string(reqBytes)))
+	}
+
+	command := strings.Join(args, " \\\n  ")
+	if !includeSecrets {
+		command = c.redact(command)
+	}
+	return command, nil
+}
+
+// curlFormArgs returns the curl -F and --form-string arguments of a multipart body
+// File parts with inline content have no curl equivalent without a file, so they are rejected
+func curlFormArgs(body map[string]interface{}) ([]string, error) {
+	keys := make([]string, 0, len(body))
+	for key := range body {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var args []string
+	for _, key := range keys {
+		file, isFile, err := templates.ParseMultipartFile(key, body[key])
+		if err != nil {
+			return nil, err
+		}
+		if !isFile {
+			value, err := formValue(body[key])
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode multipart field '%s': %w", key, err)
+			}
+			args = append(args, "--form-string "+utils.ShellQuote(key+"="+value))
+			continue
+		}
+		if file.Path == "" {
+			return nil, fmt.Errorf("multipart file part '%s' has inline content, which a curl command cannot include", key)
+		}
+		args = append(args, "-F "+utils.ShellQuote(fmt.Sprintf("%s=@%s;filename=%s;type=%s", key, file.Path, file.Filename, file.ContentType)))
+	}
+	return args, nil
+}
@@ -0,0 +1,66 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// gzipMagic is the header of gzip streams, used to detect bodies compressed without a Content-Encoding
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// decodeResponseBody returns a reader that decompresses the response body according to Content-Encoding
+// Bodies already decompressed by the transport are returned as-is. With detectGzip, gzip bodies are
+// detected by their magic bytes when the server compresses without saying so; binary responses
+// leave it off, as a downloaded .gz file starts with the same bytes
+func decodeResponseBody(resp *http.Response, detectGzip bool) (io.Reader, error) {
+	body := bufio.NewReader(resp.Body)
+	if resp.Uncompressed {
+		return body, nil
+	}
+
+	switch strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))) {
+	case "gzip", "x-gzip":
+		return newGzipReader(body)
+	case "deflate":
+		// HTTP deflate is zlib-wrapped, but some servers send raw deflate data
+		if header, _ := body.Peek(2); isZlibHeader(header) {
+			reader, err := zlib.NewReader(body)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode deflate response: %w", err)
+			}
+			return reader, nil
+		}
+		return flate.NewReader(body), nil
+	case "", "identity":
+		if !detectGzip {
+			return body, nil
+		}
+		if header, _ := body.Peek(len(gzipMagic)); bytes.Equal(header, gzipMagic) {
+			return newGzipReader(body)
+		}
+		return body, nil
+	default:
+		return nil, fmt.Errorf("unsupported response Content-Encoding: %s", resp.Header.Get("Content-Encoding"))
+	}
+}
+
+// newGzipReader creates a gzip reader with a descriptive error
+func newGzipReader(r io.Reader) (io.Reader, error) {
+	reader, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode gzip response: %w", err)
+	}
+	return reader, nil
+}
+
+// isZlibHeader reports whether header is a zlib stream header (deflate method with a valid check value)
+func isZlibHeader(header []byte) bool {
+	return len(header) == 2 && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0
+}
@@ -0,0 +1,172 @@
+package llm
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nodewee/llm-caller/pkg/templates"
+)
+
+const encodingFixture = `{"choices":[{"message":{"content":"Hello"}}]}`
+
+func gzipData(t *testing.T, data string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write([]byte(data))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func zlibData(t *testing.T, data string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	w.Write([]byte(data))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func rawDeflateData(t *testing.T, data string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.DefaultCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte(data))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecodeResponseBody(t *testing.T) {
+	tests := []struct {
+		name     string
+		encoding string
+		body     func(t *testing.T, data string) []byte
+		// transportDecodes leaves Accept-Encoding to the transport, which then decompresses gzip itself
+		transportDecodes bool
+	}{
+		{name: "gzip", encoding: "gzip", body: gzipData},
+		{name: "zlib deflate", encoding: "deflate", body: zlibData},
+		{name: "raw deflate", encoding: "deflate", body: rawDeflateData},
+		{name: "undeclared gzip", encoding: "", body: gzipData},
+		{name: "identity", encoding: "", body: func(t *testing.T, data string) []byte { return []byte(data) }},
+		{name: "decompressed by transport", encoding: "gzip", body: gzipData, transportDecodes: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := tt.body(t, encodingFixture)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.encoding != "" {
+					w.Header().Set("Content-Encoding", tt.encoding)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.Write(body)
+			}))
+			defer server.Close()
+
+			req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.transportDecodes {
+				req.Header.Set("Accept-Encoding", "gzip, deflate")
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.Uncompressed != tt.transportDecodes {
+				t.Fatalf("resp.Uncompressed = %v, want %v", resp.Uncompressed, tt.transportDecodes)
+			}
+
+			reader, err := decodeResponseBody(resp, true)
+			if err != nil {
+				t.Fatalf("decodeResponseBody: %v", err)
+			}
+			data, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatalf("read decoded body: %v", err)
+			}
+			if string(data) != encodingFixture {
+				t.Errorf("decoded body = %q, want %q", data, encodingFixture)
+			}
+		})
+	}
+}
+
+func TestBinaryResponseKeepsGzipFile(t *testing.T) {
+	archive := gzipData(t, "file content")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Write(archive)
+	}))
+	defer server.Close()
+
+	template, err := templates.LoadTemplateFromJSON(fmt.Sprintf(
+		`{"provider": "custom", "request": {"url": %q, "method": "GET"}, "response": {"type": "binary"}}`, server.URL))
+	if err != nil {
+		t.Fatalf("LoadTemplateFromJSON: %v", err)
+	}
+	result, err := newTestClient(t, ClientOptions{}).Call(template)
+	if err != nil {
+		t.Fatalf("Call: %v", err)
+	}
+	if !bytes.Equal(result.Body, archive) {
+		t.Errorf("binary body = %q, want the gzip file %q", result.Body, archive)
+	}
+}
+
+func TestDecodeResponseBodyUndeclaredGzipWithoutDetection(t *testing.T) {
+	archive := gzipData(t, encodingFixture)
+	resp := &http.Response{
+		Header: http.Header{},
+		Body:   io.NopCloser(bytes.NewReader(archive)),
+	}
+	reader, err := decodeResponseBody(resp, false)
+	if err != nil {
+		t.Fatalf("decodeResponseBody: %v", err)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	if !bytes.Equal(data, archive) {
+		t.Errorf("body = %q, want it unchanged", data)
+	}
+}
+
+func TestDecodeResponseBodyUnsupportedEncoding(t *testing.T) {
+	resp := &http.Response{
+		Header: http.Header{"Content-Encoding": []string{"br"}},
+		Body:   io.NopCloser(bytes.NewReader([]byte("data"))),
+	}
+	if _, err := decodeResponseBody(resp, true); err == nil {
+		t.Error("decodeResponseBody accepted Content-Encoding br")
+	}
+}
+
+func TestIsZlibHeader(t *testing.T) {
+	if !isZlibHeader(zlibData(t, "x")[:2]) {
+		t.Error("zlib stream header not recognized")
+	}
+	if isZlibHeader(rawDeflateData(t, encodingFixture)[:2]) {
+		t.Error("raw deflate data recognized as zlib")
+	}
+}
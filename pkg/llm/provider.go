@@ -6,10 +6,10 @@ import (
 
 // Provider is an interface for LLM providers
 type Provider interface {
-	Call(template *templates.Template) (string, error)
+	Call(template *templates.Template) (*Result, error)
 }
 
 // GetProvider returns a generic provider for any template
-func GetProvider(template *templates.Template, apiKey string) (Provider, error) {
-	return NewGenericClient(apiKey)
+func GetProvider(template *templates.Template, apiKey string, opts ClientOptions) (Provider, error) {
+	return NewGenericClient(apiKey, opts)
 }
@@ -0,0 +1,153 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/nodewee/llm-caller/pkg/templates"
+	"github.com/nodewee/llm-caller/pkg/transform"
+)
+
+// streamDone is the sentinel OpenAI-compatible APIs send as the data of the last event of a stream
+const streamDone = "[DONE]"
+
+// streamDeltaPaths are the paths of the content delta in the chunks of an OpenAI-compatible stream,
+// for chat completions and legacy completions
+var streamDeltaPaths = []string{"choices[0].delta.content", "choices[0].text"}
+
+// streamErrorPath is where chunks report an error when the template has no error_path
+const streamErrorPath = "error"
+
+// maxStreamEventBytes limits the size of a single line of an event stream
+const maxStreamEventBytes = 4 << 20
+
+// isEventStream reports whether the response is a server-sent event stream, e.g. of a request with "stream": true
+func isEventStream(resp *http.Response) bool {
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return err == nil && mediaType == "text/event-stream"
+}
+
+// isEventStreamBody reports whether a saved response body is an event stream, starting with a field or a comment
+func isEventStreamBody(body []byte) bool {
+	body = bytes.TrimLeft(body, " \t\r\n")
+	for _, prefix := range []string{"data:", "event:", "id:", ":"} {
+		if bytes.HasPrefix(body, []byte(prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+// readEventStream reads an OpenAI-compatible event stream and joins the content deltas of its chunks
+// Each delta is passed to OnDelta as it arrives. The stream ends with the [DONE] sentinel or the end of the body.
+// With stream_options.include_usage the usage arrives in a final chunk with an empty choices array;
+// the last usage reported is kept, as some APIs repeat the running totals in every chunk.
+// The raw stream is returned with errors so callers can report it
+func (c *GenericClient) readEventStream(template *templates.Template, r io.Reader, statusCode int) (*Result, []byte, error) {
+	if len(template.Response.Paths) > 0 || template.Response.Mode != "" || template.Response.Expr != "" {
+		return nil, nil, fmt.Errorf("response.paths, response.mode and response.expr cannot extract a streamed response, remove \"stream\" from the request body")
+	}
+
+	limited := r
+	if c.MaxResponseBytes > 0 {
+		// Read one byte past the limit to detect oversized streams
+		limited = io.LimitReader(r, c.MaxResponseBytes+1)
+	}
+	var raw bytes.Buffer
+	scanner := bufio.NewScanner(io.TeeReader(limited, &raw))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxStreamEventBytes)
+
+	var content strings.Builder
+	result := &Result{StatusCode: statusCode}
+	errorPath := template.Response.ErrorPath
+	if errorPath == "" {
+		errorPath = streamErrorPath
+	}
+
+	// handleEvent processes the data of one event, it returns false at the end of the stream
+	handleEvent := func(data string) (bool, error) {
+		data = strings.TrimSpace(data)
+		if data == streamDone {
+			return false, nil
+		}
+		if data == "" {
+			return true, nil
+		}
+
+		chunk, err := parseResponseBody([]byte(data))
+		if err != nil {
+			return false, fmt.Errorf("failed to parse stream chunk: %w", err)
+		}
+		if message, ok := errorMessageAt(chunk, errorPath); ok {
+			return false, fmt.Errorf("API returned an error in the stream: %s", message)
+		}
+		result.Response = chunk
+
+		// The usage-only chunk has no choices, so it carries no delta
+		if usage, ok := ParseUsage(chunk); ok {
+			result.Usage = &usage
+		}
+		for _, path := range streamDeltaPaths {
+			if delta, ok := stringAtPath(chunk, path); ok {
+				if delta != "" {
+					content.WriteString(delta)
+					if c.OnDelta != nil {
+						c.OnDelta(delta)
+					}
+				}
+				break
+			}
+		}
+		return true, nil
+	}
+
+	// Events are separated by blank lines, the data lines of an event are joined with newlines
+	var data []string
+	more := true
+	for more && scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if line == "" {
+			if len(data) > 0 {
+				var err error
+				if more, err = handleEvent(strings.Join(data, "\n")); err != nil {
+					return nil, raw.Bytes(), err
+				}
+				data = data[:0]
+			}
+			continue
+		}
+		// Comments (":") and the event, id and retry fields carry no content
+		if value, ok := strings.CutPrefix(line, "data:"); ok {
+			data = append(data, strings.TrimPrefix(value, " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, raw.Bytes(), fmt.Errorf("failed to read response stream: %w", err)
+	}
+	if c.MaxResponseBytes > 0 && int64(raw.Len()) > c.MaxResponseBytes {
+		return nil, raw.Bytes(), fmt.Errorf("response body exceeds the limit of %d bytes", c.MaxResponseBytes)
+	}
+	if more && len(data) > 0 {
+		// The last event is not followed by a blank line
+		if _, err := handleEvent(strings.Join(data, "\n")); err != nil {
+			return nil, raw.Bytes(), err
+		}
+	}
+	result.Body = raw.Bytes()
+
+	// Apply response transforms and validate the joined content like a complete response
+	text, err := transform.Apply(content.String(), template.Response.Transform)
+	if err != nil {
+		return nil, result.Body, err
+	}
+	if err := template.ValidateResponseContent(text); err != nil {
+		return nil, result.Body, err
+	}
+	result.Content = text
+	return result, result.Body, nil
+}
@@ -0,0 +1,164 @@
+package llm
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nodewee/llm-caller/pkg/templates"
+)
+
+// streamServer answers every request with an event stream of the given body
+func streamServer(t *testing.T, stream string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+		io.WriteString(w, stream)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestStreamedResponse(t *testing.T) {
+	tests := []struct {
+		name      string
+		stream    string
+		want      string
+		wantUsage *Usage
+		wantErr   string
+	}{
+		{
+			name: "usage chunk before done",
+			stream: "data: {\"choices\":[{\"delta\":{\"role\":\"assistant\"}}],\"usage\":null}\n\n" +
+				"data: {\"choices\":[{\"delta\":{\"content\":\"Hel\"}}],\"usage\":null}\n\n" +
+				"data: {\"choices\":[{\"delta\":{\"content\":\"lo\"}}],\"usage\":null}\n\n" +
+				"data: {\"choices\":[],\"usage\":{\"prompt_tokens\":9,\"completion_tokens\":2,\"total_tokens\":11}}\n\n" +
+				"data: [DONE]\n\n",
+			want:      "Hello",
+			wantUsage: &Usage{PromptTokens: 9, CompletionTokens: 2, TotalTokens: 11},
+		},
+		{
+			name:   "no usage reported",
+			stream: "data: {\"choices\":[{\"delta\":{\"content\":\"Hi\"}}]}\n\ndata: [DONE]\n\n",
+			want:   "Hi",
+		},
+		{
+			name: "running usage keeps the last",
+			stream: "data: {\"choices\":[{\"delta\":{\"content\":\"a\"}}],\"usage\":{\"prompt_tokens\":5,\"completion_tokens\":1}}\n\n" +
+				"data: {\"choices\":[{\"delta\":{\"content\":\"b\"}}],\"usage\":{\"prompt_tokens\":5,\"completion_tokens\":2}}\n\n" +
+				"data: [DONE]\n\n",
+			want:      "ab",
+			wantUsage: &Usage{PromptTokens: 5, CompletionTokens: 2, TotalTokens: 7},
+		},
+		{
+			name:   "legacy completions",
+			stream: "data: {\"choices\":[{\"text\":\"one \"}]}\n\ndata: {\"choices\":[{\"text\":\"two\"}]}\n\ndata: [DONE]\n\n",
+			want:   "one two",
+		},
+		{
+			name:   "comments, event fields and CRLF",
+			stream: ": keep-alive\r\n\r\nevent: message\r\nid: 1\r\ndata: {\"choices\":[{\"delta\":{\"content\":\"x\"}}]}\r\n\r\ndata: [DONE]\r\n\r\n",
+			want:   "x",
+		},
+		{
+			name:   "last event without blank line or done",
+			stream: "data: {\"choices\":[{\"delta\":{\"content\":\"a\"}}]}\n\ndata: {\"choices\":[{\"delta\":{\"content\":\"b\"}}]}",
+			want:   "ab",
+		},
+		{
+			name:   "data after done is ignored",
+			stream: "data: {\"choices\":[{\"delta\":{\"content\":\"a\"}}]}\n\ndata: [DONE]\n\ndata: not json\n\n",
+			want:   "a",
+		},
+		{
+			name:    "error chunk",
+			stream:  "data: {\"choices\":[{\"delta\":{\"content\":\"a\"}}]}\n\ndata: {\"error\":{\"message\":\"overloaded\"}}\n\n",
+			wantErr: "overloaded",
+		},
+		{
+			name:    "invalid chunk",
+			stream:  "data: {\"choices\":\n\n",
+			wantErr: "failed to parse stream chunk",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := streamServer(t, tt.stream)
+			template, err := templates.LoadTemplateFromJSON(fmt.Sprintf(`{"provider": "openai", "request": {"url": %q, "body": {"stream": true}}}`, server.URL))
+			if err != nil {
+				t.Fatalf("LoadTemplateFromJSON: %v", err)
+			}
+
+			var deltas []string
+			client := newTestClient(t, ClientOptions{OnDelta: func(delta string) { deltas = append(deltas, delta) }})
+			result, err := client.Call(template)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Call error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Call: %v", err)
+			}
+			if result.Content != tt.want {
+				t.Errorf("Content = %q, want %q", result.Content, tt.want)
+			}
+			if got := strings.Join(deltas, ""); got != tt.want {
+				t.Errorf("deltas = %q, want them to join to %q", deltas, tt.want)
+			}
+			// Reading stops at [DONE], so the raw body may end before data sent after it
+			if len(result.Body) == 0 || !strings.HasPrefix(tt.stream, string(result.Body)) {
+				t.Errorf("Body = %q, want the raw stream", result.Body)
+			}
+			switch {
+			case tt.wantUsage == nil && result.Usage != nil:
+				t.Errorf("Usage = %+v, want nil", result.Usage)
+			case tt.wantUsage != nil && (result.Usage == nil || *result.Usage != *tt.wantUsage):
+				t.Errorf("Usage = %+v, want %+v", result.Usage, tt.wantUsage)
+			}
+		})
+	}
+}
+
+func TestStreamedResponseLimits(t *testing.T) {
+	server := streamServer(t, "data: {\"choices\":[{\"delta\":{\"content\":\"a long enough response\"}}]}\n\ndata: [DONE]\n\n")
+	template, err := templates.LoadTemplateFromJSON(fmt.Sprintf(`{"provider": "openai", "request": {"url": %q, "body": {}}}`, server.URL))
+	if err != nil {
+		t.Fatalf("LoadTemplateFromJSON: %v", err)
+	}
+	if _, err := newTestClient(t, ClientOptions{MaxResponseBytes: 20}).Call(template); err == nil || !strings.Contains(err.Error(), "exceeds the limit") {
+		t.Errorf("Call error = %v, want the size limit error", err)
+	}
+
+	paths, err := templates.LoadTemplateFromJSON(fmt.Sprintf(`{"provider": "openai", "request": {"url": %q, "body": {}}, "response": {"paths": {"a": "b"}}}`, server.URL))
+	if err != nil {
+		t.Fatalf("LoadTemplateFromJSON: %v", err)
+	}
+	if _, err := newTestClient(t, ClientOptions{}).Call(paths); err == nil || !strings.Contains(err.Error(), "streamed response") {
+		t.Errorf("Call error = %v, want response.paths to be rejected", err)
+	}
+}
+
+func TestProcessSavedStream(t *testing.T) {
+	template, err := templates.LoadTemplateFromJSON(`{"provider": "openai", "request": {"url": "https://api.example.com", "body": {}}, "response": {"transform": ["trim"]}}`)
+	if err != nil {
+		t.Fatalf("LoadTemplateFromJSON: %v", err)
+	}
+	body := "data: {\"choices\":[{\"delta\":{\"content\":\" saved \"}}]}\n\n" +
+		"data: {\"choices\":[],\"usage\":{\"prompt_tokens\":1,\"completion_tokens\":1}}\n\ndata: [DONE]\n\n"
+	result, err := ProcessResponse(template, []byte(body))
+	if err != nil {
+		t.Fatalf("ProcessResponse: %v", err)
+	}
+	if result.Content != "saved" {
+		t.Errorf("Content = %q, want the transformed stream content %q", result.Content, "saved")
+	}
+	if result.Usage == nil || result.Usage.TotalTokens != 2 {
+		t.Errorf("Usage = %+v, want 2 total tokens", result.Usage)
+	}
+}
@@ -0,0 +1,59 @@
+package llm
+
+import "unicode"
+
+// charsPerToken is the average number of characters of a word per token in English text
+const charsPerToken = 4
+
+// EstimateTokens returns a rough token count of the text strings in a request body
+// Keys, numbers and booleans are ignored, only string values are counted
+func EstimateTokens(body interface{}) int {
+	switch v := body.(type) {
+	case string:
+		return EstimateTextTokens(v)
+	case map[string]interface{}:
+		total := 0
+		for _, value := range v {
+			total += EstimateTokens(value)
+		}
+		return total
+	case []interface{}:
+		total := 0
+		for _, item := range v {
+			total += EstimateTokens(item)
+		}
+		return total
+	default:
+		return 0
+	}
+}
+
+// EstimateTextTokens returns a rough token count of a text, without a model specific tokenizer
+// Words count one token per 4 characters, CJK characters and punctuation one token each
+func EstimateTextTokens(text string) int {
+	tokens := 0
+	wordLength := 0
+	endWord := func() {
+		if wordLength > 0 {
+			tokens += (wordLength + charsPerToken - 1) / charsPerToken
+			wordLength = 0
+		}
+	}
+
+	for _, r := range text {
+		switch {
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			endWord()
+			tokens++
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r):
+			wordLength++
+		case unicode.IsSpace(r):
+			endWord()
+		default:
+			endWord()
+			tokens++
+		}
+	}
+	endWord()
+	return tokens
+}
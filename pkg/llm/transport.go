@@ -0,0 +1,60 @@
+package llm
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/nodewee/llm-caller/pkg/utils"
+)
+
+// transportKey identifies the settings an HTTP transport is built from
+type transportKey struct {
+	proxyURL            string
+	caCertFile          string
+	insecure            bool
+	maxIdleConnsPerHost int
+}
+
+// sharedTransports holds one transport per setting, so every client of the process reuses its pooled
+// connections: batch and repeat jobs, pages, failover keys and fallback templates
+var (
+	sharedTransportsMu sync.Mutex
+	sharedTransports   = make(map[transportKey]*http.Transport)
+)
+
+// sharedTransport returns the process-wide HTTP transport for the proxy, TLS and pooling options
+func sharedTransport(opts ClientOptions) (*http.Transport, error) {
+	key := transportKey{
+		proxyURL:            opts.ProxyURL,
+		caCertFile:          opts.CACertFile,
+		insecure:            opts.Insecure,
+		maxIdleConnsPerHost: opts.MaxIdleConnsPerHost,
+	}
+
+	sharedTransportsMu.Lock()
+	defer sharedTransportsMu.Unlock()
+	if transport, ok := sharedTransports[key]; ok {
+		return transport, nil
+	}
+
+	transport, err := utils.NewHTTPTransport(opts.ProxyURL)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig, err := utils.NewTLSConfig(opts.CACertFile, opts.Insecure)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+	if opts.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = opts.MaxIdleConnsPerHost
+		if transport.MaxIdleConns < opts.MaxIdleConnsPerHost {
+			transport.MaxIdleConns = opts.MaxIdleConnsPerHost
+		}
+	}
+
+	sharedTransports[key] = transport
+	return transport, nil
+}
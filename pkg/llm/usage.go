@@ -0,0 +1,51 @@
+package llm
+
+// Usage contains the token counts reported by the API
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// Add accumulates the token counts of another usage
+func (u *Usage) Add(other Usage) {
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.TotalTokens += other.TotalTokens
+}
+
+// ParseUsage extracts token usage from a parsed response
+// Supports OpenAI-compatible (prompt_tokens/completion_tokens) and Anthropic (input_tokens/output_tokens) formats
+func ParseUsage(response interface{}) (Usage, bool) {
+	usageMap, ok := navigateToField(response, "usage").(map[string]interface{})
+	if !ok {
+		return Usage{}, false
+	}
+
+	var usage Usage
+	prompt, hasPrompt := tokenCount(usageMap, "prompt_tokens", "input_tokens")
+	completion, hasCompletion := tokenCount(usageMap, "completion_tokens", "output_tokens")
+	if !hasPrompt && !hasCompletion {
+		return Usage{}, false
+	}
+	usage.PromptTokens = prompt
+	usage.CompletionTokens = completion
+
+	if total, ok := tokenCount(usageMap, "total_tokens"); ok {
+		usage.TotalTokens = total
+	} else {
+		usage.TotalTokens = prompt + completion
+	}
+
+	return usage, true
+}
+
+// tokenCount returns the first numeric field found among the given names
+func tokenCount(usage map[string]interface{}, names ...string) (int, bool) {
+	for _, name := range names {
+		if value, ok := usage[name].(float64); ok {
+			return int(value), true
+		}
+	}
+	return 0, false
+}
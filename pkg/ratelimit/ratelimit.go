@@ -0,0 +1,137 @@
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nodewee/llm-caller/pkg/utils"
+)
+
+// Rate is a number of requests allowed per interval
+type Rate struct {
+	Count    float64
+	Interval time.Duration
+}
+
+// ParseRate parses a rate such as "3/s", "60/m" or "1000/h"
+func ParseRate(spec string) (Rate, error) {
+	count, unit, ok := strings.Cut(strings.TrimSpace(spec), "/")
+	if !ok {
+		return Rate{}, fmt.Errorf("invalid rate '%s', expected <count>/<s|m|h>, e.g. 3/s", spec)
+	}
+
+	n, err := strconv.ParseFloat(strings.TrimSpace(count), 64)
+	if err != nil || n <= 0 {
+		return Rate{}, fmt.Errorf("invalid rate '%s', the count must be a positive number", spec)
+	}
+
+	var interval time.Duration
+	switch strings.TrimSpace(unit) {
+	case "s":
+		interval = time.Second
+	case "m":
+		interval = time.Minute
+	case "h":
+		interval = time.Hour
+	default:
+		return Rate{}, fmt.Errorf("invalid rate '%s', the unit must be s, m or h", spec)
+	}
+	return Rate{Count: n, Interval: interval}, nil
+}
+
+// perSecond returns the refill rate of the bucket in tokens per second
+func (r Rate) perSecond() float64 {
+	return r.Count / r.Interval.Seconds()
+}
+
+// bucket is the persisted token bucket state of one key
+type bucket struct {
+	Tokens  float64   `json:"tokens"`
+	Updated time.Time `json:"updated"`
+}
+
+// Limiter is a token bucket shared by all processes through a state file
+// The bucket holds up to Rate.Count tokens and refills continuously at the rate
+type Limiter struct {
+	stateFile string
+	key       string
+	rate      Rate
+}
+
+// New creates a limiter for key (e.g. a provider name) whose state is kept in stateFile
+func New(stateFile, key string, rate Rate) *Limiter {
+	return &Limiter{stateFile: stateFile, key: key, rate: rate}
+}
+
+// Wait takes a token from the bucket, sleeping until it is available, and returns the time waited
+// The token is reserved under the state file lock, so concurrent processes wait in turn instead of racing.
+// Cancelling ctx (e.g. Ctrl-C) ends the wait with the context's error, the reserved token stays used
+func (l *Limiter) Wait(ctx context.Context) (time.Duration, error) {
+	wait, err := l.reserve(time.Now())
+	if err != nil {
+		return 0, err
+	}
+	if wait <= 0 {
+		return 0, nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return wait, nil
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+// reserve takes a token, possibly going into debt, and returns how long the caller must wait for it
+func (l *Limiter) reserve(now time.Time) (time.Duration, error) {
+	if err := utils.CreateDirWithPlatformPermissions(filepath.Dir(l.stateFile)); err != nil {
+		return 0, fmt.Errorf("failed to create rate limit directory: %w", err)
+	}
+	unlock, err := utils.LockFile(l.stateFile)
+	if err != nil {
+		return 0, fmt.Errorf("failed to lock rate limit state: %w", err)
+	}
+	defer unlock()
+
+	buckets := make(map[string]bucket)
+	if data, err := os.ReadFile(l.stateFile); err == nil {
+		// A corrupt state file only resets the buckets
+		_ = json.Unmarshal(data, &buckets)
+	}
+
+	state, ok := buckets[l.key]
+	if !ok {
+		state = bucket{Tokens: l.rate.Count, Updated: now}
+	}
+	if elapsed := now.Sub(state.Updated).Seconds(); elapsed > 0 {
+		state.Tokens += elapsed * l.rate.perSecond()
+		if state.Tokens > l.rate.Count {
+			state.Tokens = l.rate.Count
+		}
+		state.Updated = now
+	}
+	state.Tokens--
+	buckets[l.key] = state
+
+	data, err := json.MarshalIndent(buckets, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	if err := os.WriteFile(l.stateFile, append(data, '\n'), utils.GetFilePermissions()); err != nil {
+		return 0, fmt.Errorf("failed to save rate limit state: %w", err)
+	}
+
+	if state.Tokens >= 0 {
+		return 0, nil
+	}
+	return time.Duration(-state.Tokens / l.rate.perSecond() * float64(time.Second)), nil
+}
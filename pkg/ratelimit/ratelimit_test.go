@@ -0,0 +1,32 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWaitCancelled(t *testing.T) {
+	rate, err := ParseRate("1/h")
+	if err != nil {
+		t.Fatalf("ParseRate: %v", err)
+	}
+	limiter := New(filepath.Join(t.TempDir(), "ratelimit.json"), "test", rate)
+
+	if waited, err := limiter.Wait(context.Background()); err != nil || waited != 0 {
+		t.Fatalf("first Wait = %s, %v, want no wait", waited, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = limiter.Wait(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Wait error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Wait returned after %s, want it to end with the context", elapsed)
+	}
+}
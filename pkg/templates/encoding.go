@@ -0,0 +1,126 @@
+package templates
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+)
+
+// Byte order marks that editors, notably on Windows, write at the start of text files
+var (
+	utf8BOM    = []byte{0xEF, 0xBB, 0xBF}
+	utf16LEBOM = []byte{0xFF, 0xFE}
+	utf16BEBOM = []byte{0xFE, 0xFF}
+)
+
+// maxSnippetLength limits the line shown around a JSON syntax error
+const maxSnippetLength = 80
+
+// decodeTemplateText removes a UTF-8 byte order mark and checks that template content is UTF-8
+func decodeTemplateText(data []byte) ([]byte, error) {
+	if bytes.HasPrefix(data, utf16LEBOM) || bytes.HasPrefix(data, utf16BEBOM) {
+		return nil, fmt.Errorf("template is UTF-16 encoded, save it as UTF-8")
+	}
+	data = bytes.TrimPrefix(data, utf8BOM)
+	if !utf8.Valid(data) {
+		offset := invalidUTF8Offset(data)
+		line, column := lineColumn(data, offset)
+		return nil, fmt.Errorf("template is not valid UTF-8 at line %d, column %d, save it as UTF-8", line, column)
+	}
+	return data, nil
+}
+
+// invalidUTF8Offset returns the byte offset of the first invalid UTF-8 sequence
+func invalidUTF8Offset(data []byte) int {
+	for offset := 0; offset < len(data); {
+		r, size := utf8.DecodeRune(data[offset:])
+		if r == utf8.RuneError && size == 1 {
+			return offset
+		}
+		offset += size
+	}
+	return len(data)
+}
+
+// jsonSyntaxError adds the line, column and text of the failing position to a JSON syntax error
+// A value of the wrong type is reported with its field and the expected type, other errors are returned unchanged
+func jsonSyntaxError(data []byte, err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return fmt.Errorf("field %s must be %s, got %s", typeErr.Field, jsonTypeName(typeErr.Type), typeErr.Value)
+	}
+
+	var syntaxErr *json.SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		return err
+	}
+
+	// The offset points just past the byte that failed
+	position := int(syntaxErr.Offset)
+	if position > 0 {
+		position--
+	}
+	if position > len(data) {
+		position = len(data)
+	}
+	line, column := lineColumn(data, position)
+
+	start := bytes.LastIndexByte(data[:position], '\n') + 1
+	end := len(data)
+	if index := bytes.IndexByte(data[position:], '\n'); index >= 0 {
+		end = position + index
+	}
+	// Tabs are shown as single spaces so the caret lines up
+	text := strings.ReplaceAll(strings.TrimRight(string(data[start:end]), "\r"), "\t", " ")
+	caret := column - 1
+	if len(text) > maxSnippetLength {
+		from := caret - maxSnippetLength/2
+		if from < 0 {
+			from = 0
+		}
+		if from+maxSnippetLength > len(text) {
+			from = len(text) - maxSnippetLength
+		}
+		text = text[from : from+maxSnippetLength]
+		caret -= from
+	}
+	if caret > len(text) {
+		caret = len(text)
+	}
+	return fmt.Errorf("%w at line %d, column %d:\n  %s\n  %s^", err, line, column, text, strings.Repeat(" ", caret))
+}
+
+// jsonTypeName describes the JSON value a template field of type t accepts
+func jsonTypeName(t reflect.Type) string {
+	if t == reflect.TypeOf(StringList(nil)) {
+		return "a string or a list of strings"
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "a list"
+	case reflect.Map, reflect.Struct:
+		return "an object"
+	default:
+		return t.String()
+	}
+}
+
+// lineColumn returns the 1-based line and byte column of an offset in data
+func lineColumn(data []byte, offset int) (int, int) {
+	line := 1 + bytes.Count(data[:offset], []byte("\n"))
+	column := offset - bytes.LastIndexByte(data[:offset], '\n')
+	return line, column
+}
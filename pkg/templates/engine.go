@@ -0,0 +1,152 @@
+package templates
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	gotemplate "text/template"
+	"text/template/parse"
+)
+
+// Template engines replacing variables in the request body
+const (
+	EngineSimple     = "simple"
+	EngineGoTemplate = "go-template"
+)
+
+// bodyTemplateFuncs are the functions available to go-template bodies
+var bodyTemplateFuncs = gotemplate.FuncMap{
+	// json encodes a value as JSON, e.g. {{json .prompt}} for a quoted and escaped string
+	"json": func(value interface{}) (string, error) {
+		data, err := json.Marshal(value)
+		return string(data), err
+	},
+}
+
+// parseBodyTemplate parses the text of a go-template body
+func parseBodyTemplate(text string) (*gotemplate.Template, error) {
+	return gotemplate.New("body").Funcs(bodyTemplateFuncs).Parse(text)
+}
+
+// loadBodyTemplate reads BodyFile as a go-template body, checking its syntax
+func (r *RequestConfig) loadBodyTemplate(baseDir string) error {
+	bodyPath := r.bodyFilePath(baseDir)
+
+	data, err := os.ReadFile(bodyPath)
+	if err != nil {
+		return fmt.Errorf("failed to read request.body_file: %w", err)
+	}
+	if _, err := parseBodyTemplate(string(data)); err != nil {
+		return fmt.Errorf("failed to parse request.body_file %s: %w", bodyPath, err)
+	}
+	r.bodyTemplate = string(data)
+	return nil
+}
+
+// renderBody executes the go-template body with the variables as data and parses the result as the JSON body
+// JSON variables are passed as their parsed values, so they can be ranged over
+func (t *Template) renderBody(replacements map[string]string) error {
+	tmpl, err := parseBodyTemplate(t.Request.bodyTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to parse request body template: %w", err)
+	}
+
+	data := make(map[string]interface{}, len(replacements))
+	for name, value := range replacements {
+		data[name] = value
+	}
+	for name, value := range t.jsonValues(replacements) {
+		data[name] = value
+	}
+
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, data); err != nil {
+		return fmt.Errorf("failed to render request body template: %w", err)
+	}
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(rendered.Bytes(), &body); err != nil {
+		return fmt.Errorf("rendered request body is not a JSON object (use {{json .name}} to quote values): %w\n%s", err, rendered.String())
+	}
+	if body == nil {
+		return fmt.Errorf("rendered request body is not a JSON object")
+	}
+
+	t.Request.Body = body
+	t.Request.bodyTemplate = ""
+	return nil
+}
+
+// bodyTemplateFields returns the sorted top-level fields a go-template body reads, i.e. its variables
+func (r RequestConfig) bodyTemplateFields() []string {
+	if r.bodyTemplate == "" {
+		return nil
+	}
+	tmpl, err := parseBodyTemplate(r.bodyTemplate)
+	if err != nil {
+		return nil
+	}
+
+	found := make(map[string]bool)
+	collectTemplateFields(tmpl.Tree.Root, true, found)
+
+	names := make([]string, 0, len(found))
+	for name := range found {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// collectTemplateFields adds the variables read by a template node to found
+// Inside range and with blocks the dot is an element rather than the variables, so only $.name is collected there
+func collectTemplateFields(node parse.Node, topLevel bool, found map[string]bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			collectTemplateFields(child, topLevel, found)
+		}
+	case *parse.ActionNode:
+		collectTemplateFields(n.Pipe, topLevel, found)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, command := range n.Cmds {
+			collectTemplateFields(command, topLevel, found)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			collectTemplateFields(arg, topLevel, found)
+		}
+	case *parse.ChainNode:
+		collectTemplateFields(n.Node, topLevel, found)
+	case *parse.FieldNode:
+		if topLevel {
+			found[n.Ident[0]] = true
+		}
+	case *parse.VariableNode:
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			found[n.Ident[1]] = true
+		}
+	case *parse.IfNode:
+		collectTemplateFields(n.Pipe, topLevel, found)
+		collectTemplateFields(n.List, topLevel, found)
+		collectTemplateFields(n.ElseList, topLevel, found)
+	case *parse.RangeNode:
+		collectTemplateFields(n.Pipe, topLevel, found)
+		collectTemplateFields(n.List, false, found)
+		collectTemplateFields(n.ElseList, topLevel, found)
+	case *parse.WithNode:
+		collectTemplateFields(n.Pipe, topLevel, found)
+		collectTemplateFields(n.List, false, found)
+		collectTemplateFields(n.ElseList, topLevel, found)
+	case *parse.TemplateNode:
+		collectTemplateFields(n.Pipe, topLevel, found)
+	}
+}
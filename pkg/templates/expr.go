@@ -0,0 +1,48 @@
+package templates
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+)
+
+// CompileExpr compiles response.expr, returning nil if none is set
+// The expression sees the parsed response as the variable response
+func (r ResponseConfig) CompileExpr() (*vm.Program, error) {
+	if r.Expr == "" {
+		return nil, nil
+	}
+	program, err := expr.Compile(r.Expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid response.expr: %w", err)
+	}
+	return program, nil
+}
+
+// EvaluateExpr evaluates response.expr against a parsed response and returns the content
+// Strings are returned as-is, other values (objects, arrays, numbers, booleans) are encoded as JSON
+func (r ResponseConfig) EvaluateExpr(response interface{}) (string, error) {
+	program, err := r.CompileExpr()
+	if err != nil {
+		return "", err
+	}
+
+	value, err := expr.Run(program, map[string]interface{}{"response": response})
+	if err != nil {
+		return "", fmt.Errorf("response.expr failed: %w", err)
+	}
+	switch value := value.(type) {
+	case nil:
+		return "", fmt.Errorf("response.expr returned no value")
+	case string:
+		return value, nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode the value of response.expr as JSON: %w", err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,78 @@
+package templates
+
+// stripJSONComments turns JSON with comments (JSONC) into standard JSON
+// Line (//) and block (/* */) comments outside strings and commas before a closing bracket
+// or brace are replaced by spaces. Newlines are kept, so syntax error positions still match the file
+func stripJSONComments(data []byte) []byte {
+	result := make([]byte, len(data))
+	copy(result, data)
+
+	inString := false
+	for i := 0; i < len(result); i++ {
+		c := result[i]
+		if inString {
+			switch c {
+			case '\\':
+				i++
+			case '"':
+				inString = false
+			}
+			continue
+		}
+
+		switch {
+		case c == '"':
+			inString = true
+		case c == '/' && i+1 < len(result) && result[i+1] == '/':
+			for i < len(result) && result[i] != '\n' {
+				result[i] = ' '
+				i++
+			}
+		case c == '/' && i+1 < len(result) && result[i+1] == '*':
+			result[i], result[i+1] = ' ', ' '
+			i += 2
+			for i < len(result) && !(result[i] == '*' && i+1 < len(result) && result[i+1] == '/') {
+				blankOut(result, i)
+				i++
+			}
+			if i < len(result) {
+				result[i], result[i+1] = ' ', ' '
+				i++
+			}
+		case c == ',':
+			if next := nextSignificant(data, i+1); next == '}' || next == ']' {
+				result[i] = ' '
+			}
+		}
+	}
+	return result
+}
+
+// blankOut replaces a comment byte with a space, keeping line breaks
+func blankOut(data []byte, i int) {
+	if data[i] != '\n' && data[i] != '\r' {
+		data[i] = ' '
+	}
+}
+
+// nextSignificant returns the first byte from start that is neither whitespace nor part of a comment
+func nextSignificant(data []byte, start int) byte {
+	for i := start; i < len(data); i++ {
+		switch {
+		case data[i] == ' ' || data[i] == '\t' || data[i] == '\n' || data[i] == '\r':
+		case data[i] == '/' && i+1 < len(data) && data[i+1] == '/':
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+		case data[i] == '/' && i+1 < len(data) && data[i+1] == '*':
+			i += 2
+			for i < len(data) && !(data[i] == '*' && i+1 < len(data) && data[i+1] == '/') {
+				i++
+			}
+			i++
+		default:
+			return data[i]
+		}
+	}
+	return 0
+}
@@ -0,0 +1,74 @@
+package templates
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// DefaultMaxPages is the number of requests a paginated call sends when the pagination block sets no max_pages
+const DefaultMaxPages = 10
+
+// PaginationConfig makes a call request the following pages of a cursor paginated API
+// The request is repeated with the cursor of each response until a response has no cursor,
+// and the content extracted from every page is joined with newlines
+type PaginationConfig struct {
+	// NextPath is the response path of the next page cursor, e.g. "meta.next_cursor"
+	// A missing, null or empty cursor ends the pagination
+	NextPath string `json:"next_path"`
+
+	// CursorParam is the top-level body field receiving the cursor, or the query parameter of bodyless requests
+	CursorParam string `json:"cursor_param"`
+
+	// MaxPages limits the number of requests of one call (default 10)
+	MaxPages int `json:"max_pages,omitempty"`
+}
+
+// validate checks the pagination block against the rest of the template
+func (p *PaginationConfig) validate(t *Template) error {
+	if p.NextPath == "" {
+		return fmt.Errorf("pagination.next_path is required")
+	}
+	if p.CursorParam == "" {
+		return fmt.Errorf("pagination.cursor_param is required")
+	}
+	if p.MaxPages < 0 {
+		return fmt.Errorf("pagination.max_pages cannot be negative")
+	}
+	if t.Request.BodyType == BodyTypeRaw && !t.Request.IsBodyless() {
+		return fmt.Errorf("pagination requires a request body with fields, request.body_type %s has none", BodyTypeRaw)
+	}
+	// Only plain text content can be joined across pages
+	if t.Response.IsRaw() || t.Response.Mode != "" || len(t.Response.Paths) > 0 {
+		return fmt.Errorf("pagination cannot be combined with response.paths, response.mode or a raw response.type")
+	}
+	return nil
+}
+
+// PageLimit returns the maximum number of requests of a paginated call
+func (p *PaginationConfig) PageLimit() int {
+	if p.MaxPages == 0 {
+		return DefaultMaxPages
+	}
+	return p.MaxPages
+}
+
+// SetCursor sets the cursor of the next page on the request, in the body or, for bodyless requests, the URL query
+func (p *PaginationConfig) SetCursor(t *Template, cursor interface{}) error {
+	if !t.Request.IsBodyless() {
+		if t.Request.Body == nil {
+			t.Request.Body = make(map[string]interface{})
+		}
+		t.Request.Body[p.CursorParam] = cursor
+		return nil
+	}
+
+	parsed, err := url.Parse(t.Request.URL)
+	if err != nil {
+		return fmt.Errorf("invalid request URL: %w", err)
+	}
+	query := parsed.Query()
+	query.Set(p.CursorParam, fmt.Sprint(cursor))
+	parsed.RawQuery = query.Encode()
+	t.Request.URL = parsed.String()
+	return nil
+}
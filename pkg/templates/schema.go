@@ -0,0 +1,58 @@
+package templates
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// responseSchemaURL is the resource name the inline response schema is registered under
+const responseSchemaURL = "mem://llm-caller/response_schema.json"
+
+// CompileResponseSchema compiles the template's inline response_schema, returning nil if none is set
+func (t *Template) CompileResponseSchema() (*jsonschema.Schema, error) {
+	if len(t.ResponseSchema) == 0 {
+		return nil, nil
+	}
+
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(responseSchemaURL, bytes.NewReader(t.ResponseSchema)); err != nil {
+		return nil, fmt.Errorf("invalid response_schema: %w", err)
+	}
+	schema, err := compiler.Compile(responseSchemaURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid response_schema: %w", err)
+	}
+	return schema, nil
+}
+
+// ValidateResponseContent parses content as JSON and validates it against the response_schema
+func (t *Template) ValidateResponseContent(content string) error {
+	schema, err := t.CompileResponseSchema()
+	if err != nil || schema == nil {
+		return err
+	}
+
+	var value interface{}
+	decoder := json.NewDecoder(strings.NewReader(content))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return fmt.Errorf("response content is not valid JSON: %w\nContent:\n%s", err, content)
+	}
+	// Only whitespace may follow the value, e.g. {"a":1} followed by more text is not a JSON document
+	var extra interface{}
+	if err := decoder.Decode(&extra); err != io.EOF {
+		return fmt.Errorf("response content is not valid JSON: unexpected data after the JSON value\nContent:\n%s", content)
+	}
+	if err := schema.Validate(value); err != nil {
+		if validationErr, ok := err.(*jsonschema.ValidationError); ok {
+			return fmt.Errorf("response content does not match response_schema:\n%#v\nContent:\n%s", validationErr, content)
+		}
+		return fmt.Errorf("response content does not match response_schema: %w\nContent:\n%s", err, content)
+	}
+	return nil
+}
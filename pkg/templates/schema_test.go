@@ -0,0 +1,30 @@
+package templates
+
+import "testing"
+
+func TestValidateResponseContent(t *testing.T) {
+	template := mustLoad(t, `{
+		"provider": "openai",
+		"request": {"url": "https://api.example.com", "body": {}},
+		"response_schema": {"type": "object", "required": ["a"]}
+	}`)
+
+	tests := []struct {
+		content string
+		wantErr bool
+	}{
+		{content: `{"a": 1}`},
+		{content: "  {\"a\": 1}\n\n"},
+		{content: `{"b": 1}`, wantErr: true},
+		{content: `not json`, wantErr: true},
+		{content: `{"a": 1} trailing garbage`, wantErr: true},
+		{content: `{"a": 1} {"a": 2}`, wantErr: true},
+		{content: `{"a": 1} }`, wantErr: true},
+	}
+	for _, tt := range tests {
+		err := template.ValidateResponseContent(tt.content)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateResponseContent(%q) error = %v, want error %v", tt.content, err, tt.wantErr)
+		}
+	}
+}
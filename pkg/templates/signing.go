@@ -0,0 +1,119 @@
+package templates
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// SigningAlgorithmHMACSHA256 signs requests with HMAC-SHA256
+const SigningAlgorithmHMACSHA256 = "hmac-sha256"
+
+// Signed request parts of the signing block
+const (
+	SigningFieldTimestamp = "timestamp"
+	SigningFieldMethod    = "method"
+	SigningFieldPath      = "path"
+	SigningFieldBody      = "body"
+)
+
+// Default settings of the signing block
+const (
+	DefaultSigningTimestampHeader = "X-Timestamp"
+	signingEncodingHex            = "hex"
+	signingEncodingBase64         = "base64"
+)
+
+// defaultSigningFields are signed when the signing block lists no fields
+var defaultSigningFields = []string{SigningFieldTimestamp, SigningFieldBody}
+
+// SigningConfig adds a signature header computed over the request, for gateways that require signed requests
+type SigningConfig struct {
+	// Algorithm is the signature algorithm, currently only "hmac-sha256"
+	Algorithm string `json:"algorithm"`
+
+	// SecretKeyRef names the secret file entry holding the signing secret
+	SecretKeyRef string `json:"secret_key_ref"`
+
+	// HeaderName is the header receiving the signature, e.g. "X-Signature"
+	HeaderName string `json:"header_name"`
+
+	// TimestampHeader is the header receiving the Unix timestamp of the request (default "X-Timestamp")
+	TimestampHeader string `json:"timestamp_header,omitempty"`
+
+	// Fields are the signed request parts joined with newlines: "timestamp", "method", "path" (with query) and "body"
+	// Defaults to timestamp and body
+	Fields []string `json:"fields,omitempty"`
+
+	// Encoding of the signature, "hex" (default) or "base64"
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// validate checks the signing block
+func (s *SigningConfig) validate() error {
+	if s.Algorithm != SigningAlgorithmHMACSHA256 {
+		return fmt.Errorf("unsupported signing.algorithm '%s', supported algorithms: %s", s.Algorithm, SigningAlgorithmHMACSHA256)
+	}
+	if s.SecretKeyRef == "" {
+		return fmt.Errorf("signing.secret_key_ref is required")
+	}
+	if s.HeaderName == "" {
+		return fmt.Errorf("signing.header_name is required")
+	}
+	for _, field := range s.Fields {
+		switch field {
+		case SigningFieldTimestamp, SigningFieldMethod, SigningFieldPath, SigningFieldBody:
+		default:
+			return fmt.Errorf("unsupported signing field '%s', supported fields: %s, %s, %s, %s",
+				field, SigningFieldTimestamp, SigningFieldMethod, SigningFieldPath, SigningFieldBody)
+		}
+	}
+	switch s.Encoding {
+	case "", signingEncodingHex, signingEncodingBase64:
+	default:
+		return fmt.Errorf("unsupported signing.encoding '%s', supported encodings: %s, %s", s.Encoding, signingEncodingHex, signingEncodingBase64)
+	}
+	return nil
+}
+
+// TimestampHeaderName returns the header carrying the request timestamp
+func (s *SigningConfig) TimestampHeaderName() string {
+	if s.TimestampHeader == "" {
+		return DefaultSigningTimestampHeader
+	}
+	return s.TimestampHeader
+}
+
+// Sign returns the signature of a request
+// path includes the query string, timestamp is the value sent in the timestamp header
+func (s *SigningConfig) Sign(secret, timestamp, method, path string, body []byte) string {
+	fields := s.Fields
+	if len(fields) == 0 {
+		fields = defaultSigningFields
+	}
+
+	parts := make([]string, len(fields))
+	for i, field := range fields {
+		switch field {
+		case SigningFieldTimestamp:
+			parts[i] = timestamp
+		case SigningFieldMethod:
+			parts[i] = method
+		case SigningFieldPath:
+			parts[i] = path
+		case SigningFieldBody:
+			parts[i] = string(body)
+		}
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strings.Join(parts, "\n")))
+	sum := mac.Sum(nil)
+	if s.Encoding == signingEncodingBase64 {
+		return base64.StdEncoding.EncodeToString(sum)
+	}
+	return hex.EncodeToString(sum)
+}
@@ -1,48 +1,278 @@
 package templates
 
 import (
+	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/nodewee/llm-caller/pkg/config"
+	"github.com/nodewee/llm-caller/pkg/transform"
 )
 
+// Request body types
+const (
+	BodyTypeJSON      = "json"
+	BodyTypeForm      = "form"
+	BodyTypeRaw       = "raw"
+	BodyTypeMultipart = "multipart"
+)
+
+// Response types
+const (
+	ResponseTypeJSON   = "json"
+	ResponseTypeText   = "text"
+	ResponseTypeBinary = "binary"
+)
+
+// responseAcceptHeaders is the Accept header sent for each non-JSON response type unless the template sets one
+var responseAcceptHeaders = map[string]string{
+	ResponseTypeText:   "text/plain, text/markdown;q=0.9, */*;q=0.8",
+	ResponseTypeBinary: "*/*",
+}
+
+// Response modes selecting a special extraction instead of Path
+const (
+	// ResponseModeToolCall extracts the tool calls of an OpenAI-style chat completion instead of the content
+	ResponseModeToolCall = "tool_call"
+
+	// ResponseModeFiles extracts the base64 encoded files of the array at Path, e.g. generated images
+	ResponseModeFiles = "files"
+)
+
+// DefaultResponsePath is the response path used when auto-detection is disabled and no path is set,
+// for providers without an entry in providerResponsePaths
+const DefaultResponsePath = "choices[0].message.content"
+
+// providerResponsePaths holds the default response path of providers whose API is not OpenAI compatible
+// It is also the fallback of auto-detection when no response.path is set
+var providerResponsePaths = map[string]string{
+	"ollama":    "response",
+	"anthropic": "content[0].text",
+	"claude":    "content[0].text",
+	"cohere":    "generations[0].text",
+	"gemini":    "candidates[0].content.parts[0].text",
+}
+
+// DefaultResponsePathFor returns the default response path for a provider, falling back to DefaultResponsePath
+func DefaultResponsePathFor(provider string) string {
+	if path, ok := providerResponsePaths[strings.ToLower(provider)]; ok {
+		return path
+	}
+	return DefaultResponsePath
+}
+
 // RequestConfig contains the HTTP request configuration
 type RequestConfig struct {
 	URL     string                 `json:"url"`
 	Method  string                 `json:"method,omitempty"`
 	Headers map[string]string      `json:"headers,omitempty"`
 	Body    map[string]interface{} `json:"body"`
+
+	// BodyType controls how the body is encoded: "json" (default), "form" (URL-encoded),
+	// "raw" (the body's single string field is sent verbatim) or "multipart" (form-data with file parts)
+	BodyType string `json:"body_type,omitempty"`
+
+	// BodyFile is a JSON file holding the body, used instead of an inline body
+	// With the go-template engine it is a text/template rendering the JSON body
+	// Relative paths are resolved against the template file's directory
+	BodyFile string `json:"body_file,omitempty"`
+
+	// bodyTemplate is the text of BodyFile with the go-template engine, rendered by ReplaceVariables
+	bodyTemplate string
 }
 
 // ResponseConfig contains the response parsing configuration
 type ResponseConfig struct {
+	// Type is the response body format: "json" (default), "text" (the body is the content, Path is ignored)
+	// or "binary" (the body is written to the output as raw bytes, e.g. audio or images)
+	Type string `json:"type,omitempty"`
+
 	// Path is the dot-notation path to extract content from the response (e.g. "choices[0].message.content")
 	Path string `json:"path,omitempty"`
 
+	// Paths extracts several named values at once (e.g. {"content": "choices[0].message.content", "finish_reason": "choices[0].finish_reason"})
+	// When set, the content is a JSON object of name to value and Path and auto-detection are not used
+	Paths map[string]string `json:"paths,omitempty"`
+
+	// Mode selects a special extraction instead of Path, "tool_call" returns the tool calls
+	// (function name and arguments) of an OpenAI-style chat completion as a JSON array
+	// "files" returns the base64 strings of the array at Path as a JSON array, e.g. "data[*].b64_json"
+	Mode string `json:"mode,omitempty"`
+
+	// Expr is an expression (https://expr-lang.org) computing the content from the parsed response, available
+	// as response, e.g. response.choices[0].message.content + "\n" + response.model
+	// When set, Path and auto-detection are not used
+	Expr string `json:"expr,omitempty"`
+
 	// AutoDetect enables automatic detection of response formats from various LLM providers
 	// When true, the system will attempt to identify common response formats before using Path
-	AutoDetect bool `json:"auto_detect,omitempty"`
+	// When omitted, auto-detection is used only if no Path is set
+	AutoDetect *bool `json:"auto_detect,omitempty"`
 
 	// ResponseFieldName specifies which field name to look for when extracting content (e.g. "response", "content")
 	// This is used as a hint for auto-detection, prioritizing this field name if specified
 	ResponseFieldName string `json:"response_field_name,omitempty"`
+
+	// ResponseField is an alias of ResponseFieldName
+	ResponseField string `json:"response_field,omitempty"`
+
+	// ErrorPath is the dot-notation path of the provider's error message (e.g. "error.message")
+	// A non-empty value fails the call even on HTTP 200, and is shown instead of the raw body on other statuses
+	ErrorPath string `json:"error_path,omitempty"`
+
+	// Transform is an ordered list of transforms applied to the extracted content
+	// Supported: "trim", "json_pretty", "extract_code", "collapse_whitespace", "dedent"
+	Transform []string `json:"transform,omitempty"`
+}
+
+// PricingConfig contains per-1k-token rates used to estimate the cost of a call
+type PricingConfig struct {
+	PromptPer1K     float64 `json:"prompt_per_1k"`
+	CompletionPer1K float64 `json:"completion_per_1k"`
+	Currency        string  `json:"currency,omitempty"`
+}
+
+// EstimateCost returns the estimated cost for the given token counts
+func (p *PricingConfig) EstimateCost(promptTokens, completionTokens int) float64 {
+	return float64(promptTokens)/1000*p.PromptPer1K + float64(completionTokens)/1000*p.CompletionPer1K
+}
+
+// Authentication types of the auth block
+const (
+	AuthTypeBearer = "bearer"
+	AuthTypeBasic  = "basic"
+	AuthTypeHeader = "header"
+)
+
+// AuthConfig sets the authentication header from the resolved API key, so templates need not spell it out
+type AuthConfig struct {
+	// Type is "bearer" (Authorization: Bearer <key>), "basic" (Authorization: Basic base64(<key>),
+	// the key being "user:password") or "header" (<HeaderName>: <Prefix><key>)
+	Type string `json:"type"`
+
+	// HeaderName overrides the Authorization header, required for the "header" type (e.g. "x-api-key")
+	HeaderName string `json:"header_name,omitempty"`
+
+	// Prefix replaces "Bearer " for the bearer type and is prepended to the key for the header type
+	Prefix string `json:"prefix,omitempty"`
+}
+
+// Header returns the header name and value carrying apiKey
+func (a *AuthConfig) Header(apiKey string) (string, string) {
+	name := a.HeaderName
+	if name == "" {
+		name = "Authorization"
+	}
+
+	switch a.Type {
+	case AuthTypeBasic:
+		return name, "Basic " + base64.StdEncoding.EncodeToString([]byte(apiKey))
+	case AuthTypeHeader:
+		return name, a.Prefix + apiKey
+	default:
+		prefix := a.Prefix
+		if prefix == "" {
+			prefix = "Bearer "
+		}
+		return name, prefix + apiKey
+	}
+}
+
+// VariableSpec documents a variable used by the template
+type VariableSpec struct {
+	Description string `json:"description,omitempty"`
+
+	// Required defaults to true when omitted
+	Required *bool `json:"required,omitempty"`
+}
+
+// IsRequired reports whether the variable must be provided
+func (v VariableSpec) IsRequired() bool {
+	return v.Required == nil || *v.Required
 }
 
 // Template represents the unified template format
 type Template struct {
+	// Include names a template whose fields are merged under this one (template files only)
+	Include string `json:"include,omitempty"`
+
+	// Engine selects how variables are replaced in the body: "simple" {{name}} substitution (default)
+	// or "go-template", which renders request.body_file with Go's text/template
+	Engine string `json:"engine,omitempty"`
+
 	Provider string         `json:"provider"`
 	Title    string         `json:"title,omitempty"`
 	Request  RequestConfig  `json:"request"`
 	Response ResponseConfig `json:"response,omitempty"`
+	Pricing  *PricingConfig `json:"pricing,omitempty"`
+
+	// Auth sets the authentication header from the API key, explicit request headers take precedence
+	Auth *AuthConfig `json:"auth,omitempty"`
+
+	// APIKeyEnv names environment variables holding the API key, e.g. "AZURE_OPENAI_KEY" or a list
+	// They are checked before the built-in <PROVIDER>_API_KEY and API_KEY variables
+	APIKeyEnv StringList `json:"api_key_env,omitempty"`
+
+	// HealthURL is requested with GET by 'call --ping' instead of sending the request, e.g. a models endpoint
+	HealthURL string `json:"health_url,omitempty"`
+
+	// Signing adds a signature header computed over the request with a secret from the secret file
+	Signing *SigningConfig `json:"signing,omitempty"`
+
+	// Pagination repeats the request with a cursor from each response and joins the content of all pages
+	Pagination *PaginationConfig `json:"pagination,omitempty"`
 
 	// Metadata fields for documentation (will be ignored during API calls)
-	Description  string   `json:"description,omitempty"`
-	APIDocument  string   `json:"api_document,omitempty"`
-	Instructions []string `json:"instructions,omitempty"`
+	Description  string                  `json:"description,omitempty"`
+	APIDocument  string                  `json:"api_document,omitempty"`
+	Instructions []string                `json:"instructions,omitempty"`
+	Variables    map[string]VariableSpec `json:"variables,omitempty"`
+
+	// Deprecated is a notice shown on stderr whenever the template is used, e.g. naming its replacement
+	Deprecated string `json:"deprecated,omitempty"`
+
+	// MinToolVersion is the lowest llm-caller version the template works with, older versions warn
+	MinToolVersion string `json:"min_tool_version,omitempty"`
+
+	// Defaults contains default variable values, overridden by --var
+	Defaults map[string]string `json:"defaults,omitempty"`
+
+	// SampleResponse is an example API response used by 'template validate' to check response.path
+	SampleResponse json.RawMessage `json:"sample_response,omitempty"`
+
+	// ResponseSchema is an inline JSON Schema the extracted content must satisfy
+	ResponseSchema json.RawMessage `json:"response_schema,omitempty"`
+
+	// jsonVariables names variables whose values are JSON documents, see SetJSONVariables
+	jsonVariables map[string]bool
+
+	// bodyParameters are top-level body fields set once variables are replaced, see OverrideBodyParameter
+	bodyParameters map[string]interface{}
+}
+
+// StringList is a list of strings that may be written as a single string in templates
+type StringList []string
+
+// UnmarshalJSON accepts a string or an array of strings
+func (l *StringList) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*l = StringList{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("expected a string or an array of strings, got %s", data)
+	}
+	*l = list
+	return nil
 }
 
 // Validate validates the template for required fields
@@ -53,19 +283,275 @@ func (t *Template) Validate() error {
 	if t.Request.URL == "" {
 		return fmt.Errorf("request.url is required in template")
 	}
-	if t.Request.Body == nil {
+	switch t.Engine {
+	case "", EngineSimple:
+	case EngineGoTemplate:
+		if t.Request.bodyTemplate == "" && !t.Request.IsBodyless() {
+			return fmt.Errorf("engine %s requires request.body_file", EngineGoTemplate)
+		}
+	default:
+		return fmt.Errorf("unsupported engine '%s', supported engines: %s, %s", t.Engine, EngineSimple, EngineGoTemplate)
+	}
+	// A go-template body exists only once rendered, its fields are checked when it is sent
+	if t.Request.Body == nil && t.Request.bodyTemplate == "" && !t.Request.IsBodyless() {
 		return fmt.Errorf("request.body is required in template")
 	}
+	switch t.Request.BodyType {
+	case "", BodyTypeJSON, BodyTypeForm:
+	case BodyTypeRaw:
+		if t.Request.IsBodyless() || t.Request.bodyTemplate != "" {
+			break
+		}
+		if _, err := RawBodyContent(t.Request.Body); err != nil {
+			return err
+		}
+	case BodyTypeMultipart:
+		for name, value := range t.Request.Body {
+			if _, _, err := ParseMultipartFile(name, value); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported request.body_type '%s', supported types: %s, %s, %s, %s",
+			t.Request.BodyType, BodyTypeJSON, BodyTypeForm, BodyTypeRaw, BodyTypeMultipart)
+	}
+	if t.Auth != nil {
+		switch t.Auth.Type {
+		case AuthTypeBearer, AuthTypeBasic:
+		case AuthTypeHeader:
+			if t.Auth.HeaderName == "" {
+				return fmt.Errorf("auth.header_name is required when auth.type is %s", AuthTypeHeader)
+			}
+		default:
+			return fmt.Errorf("unsupported auth.type '%s', supported types: %s, %s, %s",
+				t.Auth.Type, AuthTypeBearer, AuthTypeBasic, AuthTypeHeader)
+		}
+	}
+	if t.Signing != nil {
+		if err := t.Signing.validate(); err != nil {
+			return err
+		}
+	}
+	if t.MinToolVersion != "" {
+		if _, ok := parseVersion(t.MinToolVersion); !ok {
+			return fmt.Errorf("invalid min_tool_version '%s', expected a version like 1.4 or v1.4.2", t.MinToolVersion)
+		}
+	}
+	if t.Pagination != nil {
+		if err := t.Pagination.validate(t); err != nil {
+			return err
+		}
+	}
+	for _, name := range t.APIKeyEnv {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("api_key_env cannot contain empty names")
+		}
+	}
+	if err := transform.Validate(t.Response.Transform); err != nil {
+		return fmt.Errorf("invalid response.transform: %w", err)
+	}
+	switch t.Response.Type {
+	case "", ResponseTypeJSON, ResponseTypeText:
+	case ResponseTypeBinary:
+		if len(t.Response.Transform) > 0 {
+			return fmt.Errorf("response.transform cannot be used with response.type %s", ResponseTypeBinary)
+		}
+	default:
+		return fmt.Errorf("unsupported response.type '%s', supported types: %s, %s, %s",
+			t.Response.Type, ResponseTypeJSON, ResponseTypeText, ResponseTypeBinary)
+	}
+	if t.Response.IsRaw() && (len(t.Response.Paths) > 0 || t.Response.Mode != "") {
+		return fmt.Errorf("response.paths and response.mode require response.type %s", ResponseTypeJSON)
+	}
+	if t.Response.Expr != "" {
+		if t.Response.IsRaw() || len(t.Response.Paths) > 0 || t.Response.Mode != "" {
+			return fmt.Errorf("response.expr cannot be combined with response.paths, response.mode or a raw response.type")
+		}
+		if _, err := t.Response.CompileExpr(); err != nil {
+			return err
+		}
+	}
+	switch t.Response.Mode {
+	case "":
+	case ResponseModeToolCall, ResponseModeFiles:
+		if len(t.Response.Paths) > 0 {
+			return fmt.Errorf("response.mode cannot be combined with response.paths")
+		}
+		if t.Response.Mode == ResponseModeFiles && t.Response.Path == "" {
+			return fmt.Errorf("response.mode %s requires response.path pointing at an array, e.g. data[*].b64_json", ResponseModeFiles)
+		}
+	default:
+		return fmt.Errorf("unsupported response.mode '%s', supported modes: %s, %s", t.Response.Mode, ResponseModeToolCall, ResponseModeFiles)
+	}
+	if len(t.Response.Paths) > 0 {
+		for name, path := range t.Response.Paths {
+			if name == "" || path == "" {
+				return fmt.Errorf("response.paths entries must have a non-empty name and path")
+			}
+		}
+		if len(t.Response.Transform) > 0 {
+			return fmt.Errorf("response.transform cannot be combined with response.paths")
+		}
+	}
+	if _, err := t.CompileResponseSchema(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// MultipartFile describes a file part of a multipart request body
+// Exactly one of Path (a local file) or Content (e.g. a {{variable}} holding file bytes) is set
+type MultipartFile struct {
+	Path        string
+	Content     string
+	Filename    string
+	ContentType string
+}
+
+// ParseMultipartFile checks whether a multipart body entry is a file part
+// File parts are objects of the form {"type": "file", "path": "..."} or {"type": "file", "content": "{{var}}", "filename": "..."}
+func ParseMultipartFile(name string, value interface{}) (*MultipartFile, bool, error) {
+	entry, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, false, nil
+	}
+
+	if partType, _ := entry["type"].(string); partType != "file" {
+		return nil, false, fmt.Errorf("request.body field '%s' must be a plain value or a file part with \"type\": \"file\"", name)
+	}
+
+	file := &MultipartFile{}
+	file.Path, _ = entry["path"].(string)
+	file.Content, _ = entry["content"].(string)
+	file.Filename, _ = entry["filename"].(string)
+	file.ContentType, _ = entry["content_type"].(string)
+
+	_, hasPath := entry["path"]
+	_, hasContent := entry["content"]
+	if hasPath == hasContent {
+		return nil, false, fmt.Errorf("file part '%s' must have exactly one of \"path\" or \"content\"", name)
+	}
+
+	if file.Filename == "" {
+		if hasPath {
+			file.Filename = filepath.Base(file.Path)
+		} else {
+			file.Filename = name
+		}
+	}
+	if file.ContentType == "" {
+		file.ContentType = "application/octet-stream"
+	}
+
+	return file, true, nil
+}
+
+// bodyFilePath returns the path of BodyFile, resolving relative paths against baseDir
+func (r *RequestConfig) bodyFilePath(baseDir string) string {
+	bodyPath := filepath.FromSlash(r.BodyFile)
+	if !filepath.IsAbs(bodyPath) && baseDir != "" {
+		bodyPath = filepath.Join(baseDir, bodyPath)
+	}
+	return bodyPath
+}
+
+// loadBodyFile reads the JSON body from BodyFile, resolving relative paths against baseDir
+func (r *RequestConfig) loadBodyFile(baseDir string) error {
+	bodyPath := r.bodyFilePath(baseDir)
+
+	data, err := os.ReadFile(bodyPath)
+	if err != nil {
+		return fmt.Errorf("failed to read request.body_file: %w", err)
+	}
+	if err := json.Unmarshal(data, &r.Body); err != nil {
+		return fmt.Errorf("failed to parse request.body_file %s: %w", bodyPath, err)
+	}
+	if r.Body == nil {
+		return fmt.Errorf("request.body_file %s must contain a JSON object", bodyPath)
+	}
 	return nil
 }
 
+// IsRaw reports whether the response body is used as-is instead of being parsed as JSON
+func (r ResponseConfig) IsRaw() bool {
+	return r.Type == ResponseTypeText || r.Type == ResponseTypeBinary
+}
+
+// AcceptHeader returns the Accept header for the response type, or an empty string for JSON responses
+func (r ResponseConfig) AcceptHeader() string {
+	return responseAcceptHeaders[r.Type]
+}
+
+// AutoDetectEnabled reports whether the response format is auto-detected before Path is used
+func (r ResponseConfig) AutoDetectEnabled() bool {
+	if len(r.Paths) > 0 || r.Mode != "" {
+		return false
+	}
+	if r.AutoDetect != nil {
+		return *r.AutoDetect
+	}
+	return r.Path == ""
+}
+
+// isPlainContent reports whether the content is a single JSON value, not selected by response.paths,
+// response.mode or response.expr
+func (r ResponseConfig) isPlainContent() bool {
+	return !r.IsRaw() && len(r.Paths) == 0 && r.Mode == "" && r.Expr == ""
+}
+
+// IsBodyless reports whether the request is sent without a body
+// GET and HEAD requests never have one, DELETE requests only when the template sets request.body
+func (r RequestConfig) IsBodyless() bool {
+	switch strings.ToUpper(r.Method) {
+	case "GET", "HEAD":
+		return true
+	case "DELETE":
+		return r.Body == nil
+	}
+	return false
+}
+
+// RawBodyContent returns the single string field of a raw request body
+func RawBodyContent(body map[string]interface{}) (string, error) {
+	if len(body) != 1 {
+		return "", fmt.Errorf("request.body must contain exactly one string field when body_type is raw, found %d fields", len(body))
+	}
+	for key, value := range body {
+		content, ok := value.(string)
+		if !ok {
+			return "", fmt.Errorf("request.body field '%s' must be a string when body_type is raw", key)
+		}
+		return content, nil
+	}
+	return "", nil
+}
+
 // LoadTemplateFromJSON loads a template from a JSON string
 func LoadTemplateFromJSON(jsonStr string) (*Template, error) {
 	if strings.TrimSpace(jsonStr) == "" {
 		return nil, fmt.Errorf("template JSON string is empty")
 	}
 
-	return parseTemplate([]byte(jsonStr))
+	// Relative body_file paths of inline templates are resolved against the working directory
+	return parseTemplate([]byte(jsonStr), "")
+}
+
+// ParseTemplateFile parses the content of a template file without locating it, JSON or YAML by its extension
+// Includes are not resolved; relative body_file paths are resolved against the file's directory
+func ParseTemplateFile(filePath string, data []byte) (*Template, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, fmt.Errorf("template file %s is empty", filePath)
+	}
+
+	data, err := decodeTemplateText(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filePath, err)
+	}
+	data, err = templateFileJSON(filePath, data)
+	if err != nil {
+		return nil, err
+	}
+	return parseTemplate(data, filepath.Dir(filePath))
 }
 
 // LoadTemplate loads a template with priority order:
@@ -73,22 +559,57 @@ func LoadTemplateFromJSON(jsonStr string) (*Template, error) {
 // 2. Otherwise, search in user configured template directory
 // 3. Then search in default app config directory templates
 func LoadTemplate(cfg *config.Config, templatePath string) (*Template, error) {
-	// Automatically append .json extension if not present
-	if !strings.HasSuffix(templatePath, ".json") {
-		templatePath = templatePath + ".json"
+	template, _, err := LoadTemplateWithPath(cfg, templatePath)
+	return template, err
+}
+
+// LoadTemplateWithPath loads a template like LoadTemplate and also returns the absolute path of the file that was used
+func LoadTemplateWithPath(cfg *config.Config, templatePath string) (*Template, string, error) {
+	filePath, data, err := ReadTemplateFile(cfg, templatePath)
+	if err != nil {
+		return nil, "", err
 	}
 
+	data, err = resolveIncludes(cfg, filePath, data, nil)
+	if err != nil {
+		return nil, "", err
+	}
+
+	template, err := parseTemplate(data, filepath.Dir(filePath))
+	if err != nil {
+		return nil, "", err
+	}
+
+	if absPath, err := filepath.Abs(filePath); err == nil {
+		filePath = absPath
+	}
+	return template, filePath, nil
+}
+
+// ReadTemplateFile locates a template file using the LoadTemplate search order and returns its path and content
+// Aliases configured as alias.<name> are resolved first. Names without an extension match .json, then .yaml and .yml files
+func ReadTemplateFile(cfg *config.Config, templatePath string) (string, []byte, error) {
+	templatePath = cfg.ResolveTemplateAlias(templatePath)
+	candidates := CandidateFileNames(templatePath)
+
 	// Check if it's a direct path (absolute or contains path separators)
 	isDirectPath := filepath.IsAbs(templatePath) || strings.ContainsAny(templatePath, "/\\")
 
 	if isDirectPath {
-		// Normalize path for cross-platform compatibility
-		templatePath = filepath.Clean(filepath.FromSlash(templatePath))
-		data, err := os.ReadFile(templatePath)
-		if err != nil {
-			return nil, fmt.Errorf("failed to load template from direct path '%s': %w", templatePath, err)
+		var readErr error
+		for _, candidate := range candidates {
+			// Normalize path for cross-platform compatibility
+			candidate = filepath.Clean(filepath.FromSlash(candidate))
+			data, err := os.ReadFile(candidate)
+			if err == nil {
+				return candidate, data, nil
+			}
+			// Report the primary (JSON) path when no candidate exists
+			if readErr == nil || !os.IsNotExist(err) {
+				readErr = fmt.Errorf("failed to load template from direct path '%s': %w", candidate, err)
+			}
 		}
-		return parseTemplate(data)
+		return "", nil, readErr
 	}
 
 	// For template names without path separators, search in directories
@@ -97,48 +618,181 @@ func LoadTemplate(cfg *config.Config, templatePath string) (*Template, error) {
 	// First, try user configured template directory
 	userTemplateDir := cfg.GetString(config.KeyTemplateDir)
 	if userTemplateDir != "" {
-		userTemplatePath := filepath.Join(userTemplateDir, templatePath)
-		attemptedPaths = append(attemptedPaths, userTemplatePath)
-		if data, err := os.ReadFile(userTemplatePath); err == nil {
-			return parseTemplate(data)
+		for _, candidate := range candidates {
+			userTemplatePath := filepath.Join(userTemplateDir, candidate)
+			attemptedPaths = append(attemptedPaths, userTemplatePath)
+			if data, err := os.ReadFile(userTemplatePath); err == nil {
+				return userTemplatePath, data, nil
+			}
 		}
 	}
 
 	// Second, try default app config templates directory
 	defaultTemplateDir, err := config.GetDefaultTemplateDir()
 	if err == nil {
-		defaultTemplatePath := filepath.Join(defaultTemplateDir, templatePath)
-		attemptedPaths = append(attemptedPaths, defaultTemplatePath)
-		if data, err := os.ReadFile(defaultTemplatePath); err == nil {
-			return parseTemplate(data)
+		for _, candidate := range candidates {
+			defaultTemplatePath := filepath.Join(defaultTemplateDir, candidate)
+			attemptedPaths = append(attemptedPaths, defaultTemplatePath)
+			if data, err := os.ReadFile(defaultTemplatePath); err == nil {
+				return defaultTemplatePath, data, nil
+			}
 		}
 	}
 
 	// If all attempts fail, return a descriptive error
-	return nil, fmt.Errorf("template file not found, tried paths: %s", strings.Join(attemptedPaths, ", "))
+	return "", nil, fmt.Errorf("template file not found, tried paths: %s", strings.Join(attemptedPaths, ", "))
+}
+
+// resolveIncludes merges the template named by the "include" field under the template data
+// Included templates may include others; local fields override included ones and maps are merged deeply.
+// Relative include paths are resolved against the including template's directory,
+// plain names use the LoadTemplate search order. chain holds the files being resolved, to detect cycles.
+func resolveIncludes(cfg *config.Config, filePath string, data []byte, chain []string) ([]byte, error) {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		absPath = filePath
+	}
+	for _, seen := range chain {
+		if seen == absPath {
+			return nil, fmt.Errorf("circular template include: %s -> %s", strings.Join(chain, " -> "), absPath)
+		}
+	}
+	chain = append(chain, absPath)
+
+	data, err = decodeTemplateText(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filePath, err)
+	}
+	data, err = templateFileJSON(filePath, data)
+	if err != nil {
+		return nil, err
+	}
+	data = stripJSONComments(data)
+
+	var local map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&local); err != nil {
+		return nil, fmt.Errorf("failed to parse template JSON in %s: %w", filePath, jsonSyntaxError(data, err))
+	}
+
+	include, ok := local["include"].(string)
+	if !ok || include == "" {
+		return data, nil
+	}
+	delete(local, "include")
+
+	// Resolve relative include paths against the including template's directory
+	if !filepath.IsAbs(include) && strings.ContainsAny(include, "/\\") {
+		include = filepath.Join(filepath.Dir(filePath), filepath.FromSlash(include))
+	}
+
+	includePath, includeData, err := ReadTemplateFile(cfg, include)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load included template '%s': %w", include, err)
+	}
+	includeData, err = resolveIncludes(cfg, includePath, includeData, chain)
+	if err != nil {
+		return nil, err
+	}
+
+	var base map[string]interface{}
+	decoder = json.NewDecoder(bytes.NewReader(includeData))
+	decoder.UseNumber()
+	if err := decoder.Decode(&base); err != nil {
+		return nil, fmt.Errorf("failed to parse included template JSON in %s: %w", includePath, err)
+	}
+
+	// Keep the included template's body_file relative to its own directory
+	if request, ok := base["request"].(map[string]interface{}); ok {
+		if bodyFile, ok := request["body_file"].(string); ok && bodyFile != "" && !filepath.IsAbs(filepath.FromSlash(bodyFile)) {
+			request["body_file"] = filepath.Join(filepath.Dir(includePath), filepath.FromSlash(bodyFile))
+		}
+	}
+
+	merged, err := json.Marshal(mergeMaps(base, local))
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge included template: %w", err)
+	}
+	return merged, nil
+}
+
+// mergeMaps deep-merges override into base; non-map values in override replace those in base
+func mergeMaps(base, override map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(base)+len(override))
+	for key, value := range base {
+		result[key] = value
+	}
+	for key, value := range override {
+		baseMap, baseIsMap := result[key].(map[string]interface{})
+		overrideMap, overrideIsMap := value.(map[string]interface{})
+		if baseIsMap && overrideIsMap {
+			result[key] = mergeMaps(baseMap, overrideMap)
+		} else {
+			result[key] = value
+		}
+	}
+	return result
 }
 
 // parseTemplate parses template data and applies defaults and validation
-func parseTemplate(data []byte) (*Template, error) {
+// baseDir is the directory relative body_file paths are resolved against
+func parseTemplate(data []byte, baseDir string) (*Template, error) {
+	data, err := decodeTemplateText(data)
+	if err != nil {
+		return nil, err
+	}
+	data = stripJSONComments(data)
 	var template Template
 	if err := json.Unmarshal(data, &template); err != nil {
-		return nil, fmt.Errorf("failed to parse template JSON: %w", err)
+		return nil, fmt.Errorf("failed to parse template JSON: %w", jsonSyntaxError(data, err))
+	}
+
+	// Includes are resolved by LoadTemplate before parsing
+	if template.Include != "" {
+		return nil, fmt.Errorf("include is only supported for template files")
+	}
+
+	// Load the body from body_file, the go-template engine keeps it as text until variables are replaced
+	if template.Request.BodyFile != "" {
+		if template.Request.Body != nil {
+			return nil, fmt.Errorf("template validation failed: only one of request.body and request.body_file may be set")
+		}
+		loadBody := template.Request.loadBodyFile
+		if template.Engine == EngineGoTemplate {
+			loadBody = template.Request.loadBodyTemplate
+		}
+		if err := loadBody(baseDir); err != nil {
+			return nil, err
+		}
+	} else if template.Engine == EngineGoTemplate && template.Request.Body != nil {
+		return nil, fmt.Errorf("template validation failed: engine %s renders request.body_file, move the body to a template file", EngineGoTemplate)
 	}
 
 	// Set default values
 	if template.Request.Method == "" {
 		template.Request.Method = "POST"
 	}
+	template.Request.Method = strings.ToUpper(template.Request.Method)
 
-	// Set response defaults
-	if template.Response.Path == "" {
-		// Default to chat completion format if no path specified
-		template.Response.Path = "choices[0].message.content"
+	// Auto-detect the response format unless the template sets a path or disables it
+	if template.Response.AutoDetect == nil {
+		autoDetect := template.Response.Path == ""
+		template.Response.AutoDetect = &autoDetect
+	}
+	if template.Response.ResponseFieldName == "" {
+		template.Response.ResponseFieldName = template.Response.ResponseField
 	}
 
-	// Enable auto-detection by default
-	if !template.Response.AutoDetect {
-		template.Response.AutoDetect = true
+	// Set response defaults, other methods than POST are not completion requests
+	if template.Response.Path == "" && template.Request.Method == "POST" {
+		if !*template.Response.AutoDetect {
+			// Default to the provider's completion format
+			template.Response.Path = DefaultResponsePathFor(template.Provider)
+		} else if path, ok := providerResponsePaths[strings.ToLower(template.Provider)]; ok && template.Response.isPlainContent() {
+			// Auto-detection falls back to the provider's completion format when it recognizes none
+			template.Response.Path = path
+		}
 	}
 
 	// Validate the template
@@ -149,8 +803,176 @@ func parseTemplate(data []byte) (*Template, error) {
 	return &template, nil
 }
 
+// Clone returns a deep copy of the template so variables can be replaced without affecting the original
+func (t *Template) Clone() *Template {
+	clone := *t
+
+	if t.Request.Headers != nil {
+		clone.Request.Headers = make(map[string]string, len(t.Request.Headers))
+		for key, value := range t.Request.Headers {
+			clone.Request.Headers[key] = value
+		}
+	}
+	if t.Request.Body != nil {
+		clone.Request.Body = deepCopy(t.Request.Body).(map[string]interface{})
+	}
+	if t.Response.Transform != nil {
+		clone.Response.Transform = append([]string(nil), t.Response.Transform...)
+	}
+	if t.APIKeyEnv != nil {
+		clone.APIKeyEnv = append(StringList(nil), t.APIKeyEnv...)
+	}
+	if t.Response.Paths != nil {
+		clone.Response.Paths = make(map[string]string, len(t.Response.Paths))
+		for name, path := range t.Response.Paths {
+			clone.Response.Paths[name] = path
+		}
+	}
+	if t.Response.AutoDetect != nil {
+		autoDetect := *t.Response.AutoDetect
+		clone.Response.AutoDetect = &autoDetect
+	}
+	if t.Pricing != nil {
+		pricing := *t.Pricing
+		clone.Pricing = &pricing
+	}
+	if t.Auth != nil {
+		auth := *t.Auth
+		clone.Auth = &auth
+	}
+	if t.Signing != nil {
+		signing := *t.Signing
+		clone.Signing = &signing
+	}
+	if t.Pagination != nil {
+		pagination := *t.Pagination
+		clone.Pagination = &pagination
+	}
+	if t.Instructions != nil {
+		clone.Instructions = append([]string(nil), t.Instructions...)
+	}
+	if t.Variables != nil {
+		clone.Variables = make(map[string]VariableSpec, len(t.Variables))
+		for name, spec := range t.Variables {
+			clone.Variables[name] = spec
+		}
+	}
+	if t.Defaults != nil {
+		clone.Defaults = make(map[string]string, len(t.Defaults))
+		for name, value := range t.Defaults {
+			clone.Defaults[name] = value
+		}
+	}
+	if t.SampleResponse != nil {
+		clone.SampleResponse = append(json.RawMessage(nil), t.SampleResponse...)
+	}
+	if t.ResponseSchema != nil {
+		clone.ResponseSchema = append(json.RawMessage(nil), t.ResponseSchema...)
+	}
+	if t.jsonVariables != nil {
+		clone.jsonVariables = make(map[string]bool, len(t.jsonVariables))
+		for name := range t.jsonVariables {
+			clone.jsonVariables[name] = true
+		}
+	}
+	if t.bodyParameters != nil {
+		clone.bodyParameters = make(map[string]interface{}, len(t.bodyParameters))
+		for key, value := range t.bodyParameters {
+			clone.bodyParameters[key] = value
+		}
+	}
+
+	return &clone
+}
+
+// deepCopy recursively copies maps and slices of decoded JSON data
+func deepCopy(data interface{}) interface{} {
+	switch v := data.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			result[key] = deepCopy(value)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = deepCopy(item)
+		}
+		return result
+	default:
+		return v
+	}
+}
+
+// ApplyProviderDefaults merges provider defaults into the request configuration
+// Values already set in the template take precedence over the defaults
+func (t *Template) ApplyProviderDefaults(defaults config.ProviderDefaults) *Template {
+	// Prepend the base URL to relative request URLs
+	if defaults.BaseURL != "" && !strings.Contains(t.Request.URL, "://") {
+		t.Request.URL = strings.TrimSuffix(defaults.BaseURL, "/") + "/" + strings.TrimPrefix(t.Request.URL, "/")
+	}
+
+	if len(defaults.Headers) == 0 {
+		return t
+	}
+	if t.Request.Headers == nil {
+		t.Request.Headers = make(map[string]string)
+	}
+	for name, value := range defaults.Headers {
+		if !hasHeader(t.Request.Headers, name) {
+			t.Request.Headers[name] = value
+		}
+	}
+
+	return t
+}
+
+// SetBodyParameter sets a top-level request body field only if the template body already has it
+// It returns false if the field does not exist
+func (t *Template) SetBodyParameter(key string, value interface{}) bool {
+	if _, ok := t.Request.Body[key]; !ok {
+		return false
+	}
+	t.Request.Body[key] = value
+	return true
+}
+
+// OverrideBodyParameter sets a request body field, adding it if the body does not have one
+// The key may be a dot-separated path of nested objects (options.model), missing objects are created
+// It is applied by ReplaceVariables, so it also works for go-template bodies and is never substituted into
+func (t *Template) OverrideBodyParameter(key string, value interface{}) {
+	if t.bodyParameters == nil {
+		t.bodyParameters = make(map[string]interface{})
+	}
+	t.bodyParameters[key] = value
+}
+
+// hasHeader reports whether a header is set, comparing names case-insensitively
+func hasHeader(headers map[string]string, name string) bool {
+	for key := range headers {
+		if strings.EqualFold(key, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetJSONVariables marks variables whose values are JSON documents
+// A body string that is exactly "{{name}}" is replaced by the parsed JSON value instead of the text
+func (t *Template) SetJSONVariables(names map[string]bool) {
+	t.jsonVariables = make(map[string]bool, len(names))
+	for name, isJSON := range names {
+		if isJSON {
+			t.jsonVariables[name] = true
+		}
+	}
+}
+
 // ReplaceVariables replaces variables in the template with values from the replacements map
-func (t *Template) ReplaceVariables(replacements map[string]string) *Template {
+// With the go-template engine the body is rendered from its template, which fails for templates that
+// do not execute or do not produce a JSON object
+func (t *Template) ReplaceVariables(replacements map[string]string) (*Template, error) {
 	// Replace variables in request headers
 	for key, value := range t.Request.Headers {
 		t.Request.Headers[key] = replaceVariablesInString(value, replacements)
@@ -158,37 +980,275 @@ func (t *Template) ReplaceVariables(replacements map[string]string) *Template {
 
 	// Replace variables in request URL
 	t.Request.URL = replaceVariablesInString(t.Request.URL, replacements)
+	t.HealthURL = replaceVariablesInString(t.HealthURL, replacements)
+
+	// Render a go-template body, its output is not scanned for placeholders again
+	if t.Request.bodyTemplate != "" {
+		if err := t.renderBody(replacements); err != nil {
+			return t, err
+		}
+	} else if t.Request.Body != nil {
+		// Replace variables in request body, splicing JSON variables in as values
+		t.Request.Body = replaceVariablesInInterface(t.Request.Body, replacements, t.jsonValues(replacements)).(map[string]interface{})
+	}
+
+	// Apply body parameter overrides
+	if t.Request.Body != nil {
+		for key, value := range t.bodyParameters {
+			if err := setBodyPath(t.Request.Body, key, value); err != nil {
+				return t, err
+			}
+		}
+	}
 
-	// Replace variables in request body
-	t.Request.Body = replaceVariablesInInterface(t.Request.Body, replacements).(map[string]interface{})
+	return t, nil
+}
 
+// setBodyPath sets the field at a dot-separated path of a body, creating missing objects on the way
+func setBodyPath(body map[string]interface{}, path string, value interface{}) error {
+	parts := strings.Split(path, ".")
+	current := body
+	for i, part := range parts[:len(parts)-1] {
+		next, exists := current[part]
+		if !exists || next == nil {
+			child := make(map[string]interface{})
+			current[part] = child
+			current = child
+			continue
+		}
+		child, ok := next.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("cannot set request body field '%s': '%s' is not an object", path, strings.Join(parts[:i+1], "."))
+		}
+		current = child
+	}
+	current[parts[len(parts)-1]] = value
+	return nil
+}
+
+// ReplaceText replaces every occurrence of old with new in the request URL, headers and body strings
+// It is used to swap an already substituted value, e.g. to retry with another API key
+func (t *Template) ReplaceText(old, new string) *Template {
+	if old == "" {
+		return t
+	}
+
+	for key, value := range t.Request.Headers {
+		t.Request.Headers[key] = strings.ReplaceAll(value, old, new)
+	}
+	t.Request.URL = strings.ReplaceAll(t.Request.URL, old, new)
+	if t.Request.Body != nil {
+		t.Request.Body = replaceTextInInterface(t.Request.Body, old, new).(map[string]interface{})
+	}
 	return t
 }
 
-// replaceVariablesInString replaces variables in a string
+// replaceTextInInterface recursively replaces text in the strings of decoded JSON data
+func replaceTextInInterface(data interface{}, old, new string) interface{} {
+	switch v := data.(type) {
+	case string:
+		return strings.ReplaceAll(v, old, new)
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			result[key] = replaceTextInInterface(value, old, new)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = replaceTextInInterface(item, old, new)
+		}
+		return result
+	default:
+		return v
+	}
+}
+
+// jsonValues parses the values of the JSON variables present in replacements
+// Values that are not valid JSON are left to plain text substitution
+func (t *Template) jsonValues(replacements map[string]string) map[string]interface{} {
+	values := make(map[string]interface{})
+	for name := range t.jsonVariables {
+		text, ok := replacements[name]
+		if !ok {
+			continue
+		}
+		var value interface{}
+		if err := json.Unmarshal([]byte(text), &value); err == nil {
+			values[name] = value
+		}
+	}
+	return values
+}
+
+// variablePattern matches {{name}}, {{name:-fallback}} and {{name|modifier}} placeholders
+var variablePattern = regexp.MustCompile(`\{\{([^{}]+)\}\}`)
+
+// fallbackSeparator separates a placeholder's name from its inline default, as in {{model:-gpt-4o}}
+const fallbackSeparator = ":-"
+
+// modifierSeparator separates a placeholder from a modifier applied to its value, as in {{query|urlencode}}
+const modifierSeparator = "|"
+
+// placeholderModifiers transform the value substituted for a placeholder
+var placeholderModifiers = map[string]func(string) string{
+	// urlencode percent-encodes a value for a URL query parameter
+	"urlencode": url.QueryEscape,
+	// urlpath percent-encodes a value for a URL path segment
+	"urlpath": url.PathEscape,
+}
+
+// splitPlaceholder splits the text between the braces of a placeholder into its name and inline default
+// A trailing |modifier is removed; use parsePlaceholder to apply it
+func splitPlaceholder(inner string) (string, string, bool) {
+	inner, _ = splitModifier(inner)
+	return strings.Cut(inner, fallbackSeparator)
+}
+
+// splitModifier splits a known trailing |modifier from the text between the braces of a placeholder
+// Unknown modifiers are kept as part of the name, so text like {{ a | b }} is left untouched
+func splitModifier(inner string) (string, func(string) string) {
+	index := strings.LastIndex(inner, modifierSeparator)
+	if index < 0 {
+		return inner, nil
+	}
+	modifier, ok := placeholderModifiers[inner[index+len(modifierSeparator):]]
+	if !ok {
+		return inner, nil
+	}
+	return inner[:index], modifier
+}
+
+// replaceVariablesInString replaces variables in a string in a single pass
+// Placeholders without a value use their inline default if they have one, otherwise they are left as is.
+// Substituted values are never rescanned, so a value containing {{api_key}} is left intact
 func replaceVariablesInString(content string, replacements map[string]string) string {
-	result := content
-	for key, value := range replacements {
-		result = strings.ReplaceAll(result, fmt.Sprintf("{{%s}}", key), value)
+	return variablePattern.ReplaceAllStringFunc(content, func(token string) string {
+		inner, modifier := splitModifier(token[2 : len(token)-2])
+		if modifier == nil {
+			modifier = func(value string) string { return value }
+		}
+		name, fallback, hasFallback := strings.Cut(inner, fallbackSeparator)
+		if value, ok := replacements[name]; ok {
+			return modifier(value)
+		}
+		if hasFallback {
+			return modifier(fallback)
+		}
+		return token
+	})
+}
+
+// ReplaceVariablesInText replaces {{name}} placeholders in a text the way template strings are substituted
+func ReplaceVariablesInText(content string, replacements map[string]string) string {
+	return replaceVariablesInString(content, replacements)
+}
+
+// Placeholders returns the sorted unique {{name}} placeholders in the request URL, headers and body
+// For a go-template body these are the top-level fields it reads, such as .prompt
+func (t *Template) Placeholders() []string {
+	found := make(map[string]bool)
+	for inner := range t.placeholderTokens() {
+		name, _, _ := splitPlaceholder(inner)
+		found[name] = true
+	}
+	for _, name := range t.Request.bodyTemplateFields() {
+		found[name] = true
+	}
+
+	names := make([]string, 0, len(found))
+	for name := range found {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// InlineDefaults returns the inline defaults of {{name:-fallback}} placeholders by variable name
+func (t *Template) InlineDefaults() map[string]string {
+	defaults := make(map[string]string)
+	for inner := range t.placeholderTokens() {
+		if name, fallback, ok := splitPlaceholder(inner); ok {
+			defaults[name] = fallback
+		}
+	}
+	return defaults
+}
+
+// UnresolvedPlaceholders returns the sorted placeholders that ReplaceVariables would leave in the request
+// because they have neither a value in replacements nor an inline default, e.g. {{prompt}} without --var prompt
+// Placeholders are checked before substitution, so values that contain {{...}} text are not reported
+func (t *Template) UnresolvedPlaceholders(replacements map[string]string) []string {
+	var unresolved []string
+	for inner := range t.placeholderTokens() {
+		name, _, hasFallback := splitPlaceholder(inner)
+		if _, ok := replacements[name]; ok || hasFallback {
+			continue
+		}
+		unresolved = append(unresolved, "{{"+inner+"}}")
+	}
+	sort.Strings(unresolved)
+	return unresolved
+}
+
+// placeholderTokens returns the text between the braces of every placeholder in the request URL, headers and body
+func (t *Template) placeholderTokens() map[string]bool {
+	found := make(map[string]bool)
+
+	collectVariablesInString(t.Request.URL, found)
+	for _, value := range t.Request.Headers {
+		collectVariablesInString(value, found)
+	}
+	collectVariablesInInterface(t.Request.Body, found)
+	return found
+}
+
+// collectVariablesInString adds the placeholder tokens in a string to found
+func collectVariablesInString(content string, found map[string]bool) {
+	for _, match := range variablePattern.FindAllStringSubmatch(content, -1) {
+		found[match[1]] = true
+	}
+}
+
+// collectVariablesInInterface recursively collects placeholder names in any interface{} type
+func collectVariablesInInterface(data interface{}, found map[string]bool) {
+	switch v := data.(type) {
+	case string:
+		collectVariablesInString(v, found)
+	case map[string]interface{}:
+		for _, value := range v {
+			collectVariablesInInterface(value, found)
+		}
+	case []interface{}:
+		for _, item := range v {
+			collectVariablesInInterface(item, found)
+		}
 	}
-	return result
 }
 
 // replaceVariablesInInterface recursively replaces variables in any interface{} type
-func replaceVariablesInInterface(data interface{}, replacements map[string]string) interface{} {
+// Strings that consist of a single JSON variable placeholder are replaced by the variable's parsed value
+func replaceVariablesInInterface(data interface{}, replacements map[string]string, jsonValues map[string]interface{}) interface{} {
 	switch v := data.(type) {
 	case string:
+		if strings.HasPrefix(v, "{{") && strings.HasSuffix(v, "}}") {
+			name, _, _ := strings.Cut(v[2:len(v)-2], fallbackSeparator)
+			if value, ok := jsonValues[name]; ok {
+				return deepCopy(value)
+			}
+		}
 		return replaceVariablesInString(v, replacements)
 	case map[string]interface{}:
 		result := make(map[string]interface{})
 		for key, value := range v {
-			result[key] = replaceVariablesInInterface(value, replacements)
+			result[key] = replaceVariablesInInterface(value, replacements, jsonValues)
 		}
 		return result
 	case []interface{}:
 		result := make([]interface{}, len(v))
 		for i, item := range v {
-			result[i] = replaceVariablesInInterface(item, replacements)
+			result[i] = replaceVariablesInInterface(item, replacements, jsonValues)
 		}
 		return result
 	default:
@@ -197,7 +1257,7 @@ func replaceVariablesInInterface(data interface{}, replacements map[string]strin
 	}
 }
 
-// ListTemplates lists all JSON template files in the given directory
+// ListTemplates lists all JSON and YAML template files in the given directory
 func ListTemplates(templateDir string) ([]string, error) {
 	if templateDir == "" {
 		return []string{}, nil
@@ -215,7 +1275,7 @@ func ListTemplates(templateDir string) ([]string, error) {
 
 	var templates []string
 	for _, entry := range entries {
-		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") {
+		if !entry.IsDir() && IsTemplateFile(entry.Name()) {
 			templates = append(templates, entry.Name())
 		}
 	}
@@ -0,0 +1,232 @@
+package templates
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// mustLoad parses an inline JSON template or fails the test
+func mustLoad(t *testing.T, jsonStr string) *Template {
+	t.Helper()
+	template, err := LoadTemplateFromJSON(jsonStr)
+	if err != nil {
+		t.Fatalf("LoadTemplateFromJSON: %v", err)
+	}
+	return template
+}
+
+func TestReplaceVariablesDoesNotReexpandValues(t *testing.T) {
+	template := mustLoad(t, `{
+		"provider": "openai",
+		"request": {
+			"url": "https://api.example.com/v1/chat",
+			"headers": {"Authorization": "Bearer {{api_key}}"},
+			"body": {"messages": [{"role": "user", "content": "{{prompt}}"}]}
+		}
+	}`)
+
+	result, err := template.Clone().ReplaceVariables(map[string]string{
+		"prompt":  "{{api_key}}",
+		"api_key": "sk-secret",
+	})
+	if err != nil {
+		t.Fatalf("ReplaceVariables: %v", err)
+	}
+
+	if got := result.Request.Headers["Authorization"]; got != "Bearer sk-secret" {
+		t.Errorf("Authorization = %q, want %q", got, "Bearer sk-secret")
+	}
+	messages := result.Request.Body["messages"].([]interface{})
+	content := messages[0].(map[string]interface{})["content"]
+	if content != "{{api_key}}" {
+		t.Errorf("content = %q, want the literal %q", content, "{{api_key}}")
+	}
+
+	body, err := json.Marshal(result.Request.Body)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if strings.Contains(string(body), "sk-secret") {
+		t.Errorf("API key injected into the body: %s", body)
+	}
+}
+
+func TestReplaceVariablesInString(t *testing.T) {
+	tests := []struct {
+		name         string
+		content      string
+		replacements map[string]string
+		want         string
+	}{
+		{"value", "{{a}}-{{b}}", map[string]string{"a": "1", "b": "2"}, "1-2"},
+		{"value containing a placeholder", "{{a}}", map[string]string{"a": "{{b}}", "b": "2"}, "{{b}}"},
+		{"missing", "{{a}}", nil, "{{a}}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := replaceVariablesInString(tt.content, tt.replacements); got != tt.want {
+				t.Errorf("replaceVariablesInString(%q) = %q, want %q", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsBodyless(t *testing.T) {
+	body := map[string]interface{}{"a": 1}
+	tests := []struct {
+		method string
+		body   map[string]interface{}
+		want   bool
+	}{
+		{"GET", nil, true},
+		{"get", body, true},
+		{"HEAD", nil, true},
+		{"DELETE", nil, true},
+		{"DELETE", body, false},
+		{"POST", nil, false},
+		{"PUT", body, false},
+		{"PATCH", body, false},
+	}
+	for _, tt := range tests {
+		if got := (RequestConfig{Method: tt.method, Body: tt.body}).IsBodyless(); got != tt.want {
+			t.Errorf("IsBodyless(%s, body=%v) = %v, want %v", tt.method, tt.body != nil, got, tt.want)
+		}
+	}
+}
+
+func TestAutoDetectDefaultPath(t *testing.T) {
+	tests := []struct {
+		name           string
+		response       string
+		wantAutoDetect bool
+		wantPath       string
+		wantField      string
+	}{
+		{name: "omitted", response: `{}`, wantAutoDetect: true},
+		{name: "enabled", response: `{"auto_detect": true}`, wantAutoDetect: true},
+		{name: "enabled with response_field", response: `{"auto_detect": true, "response_field": "answer"}`, wantAutoDetect: true, wantField: "answer"},
+		{name: "disabled", response: `{"auto_detect": false}`, wantPath: DefaultResponsePath},
+		{name: "path", response: `{"path": "data.text"}`, wantPath: "data.text"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template := mustLoad(t, `{"provider": "openai", "request": {"url": "https://api.example.com", "body": {}}, "response": `+tt.response+`}`)
+			if got := template.Response.AutoDetectEnabled(); got != tt.wantAutoDetect {
+				t.Errorf("AutoDetectEnabled() = %v, want %v", got, tt.wantAutoDetect)
+			}
+			if template.Response.Path != tt.wantPath {
+				t.Errorf("Path = %q, want %q", template.Response.Path, tt.wantPath)
+			}
+			if template.Response.ResponseFieldName != tt.wantField {
+				t.Errorf("ResponseFieldName = %q, want %q", template.Response.ResponseFieldName, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestProviderDefaultResponsePath(t *testing.T) {
+	tests := []struct {
+		provider string
+		path     string
+		want     string
+	}{
+		{provider: "ollama", want: "response"},
+		{provider: "Ollama", want: "response"},
+		{provider: "anthropic", want: "content[0].text"},
+		{provider: "claude", want: "content[0].text"},
+		{provider: "cohere", want: "generations[0].text"},
+		{provider: "gemini", want: "candidates[0].content.parts[0].text"},
+		{provider: "openai", want: DefaultResponsePath},
+		{provider: "ollama", path: "message.content", want: "message.content"},
+		{provider: "anthropic", path: "content[-1].text", want: "content[-1].text"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.provider+" "+tt.path, func(t *testing.T) {
+			response := `{"auto_detect": false}`
+			if tt.path != "" {
+				response = fmt.Sprintf(`{"auto_detect": false, "path": %q}`, tt.path)
+			}
+			template := mustLoad(t, fmt.Sprintf(`{"provider": %q, "request": {"url": "https://api.example.com", "body": {}}, "response": %s}`, tt.provider, response))
+			if template.Response.Path != tt.want {
+				t.Errorf("Path = %q, want %q", template.Response.Path, tt.want)
+			}
+		})
+	}
+}
+
+func TestProviderResponsePathFallback(t *testing.T) {
+	tests := []struct {
+		name           string
+		provider       string
+		response       string
+		wantPath       string
+		wantAutoDetect bool
+	}{
+		{name: "gemini", provider: "gemini", response: `{}`, wantPath: "candidates[0].content.parts[0].text", wantAutoDetect: true},
+		{name: "ollama", provider: "ollama", response: `{}`, wantPath: "response", wantAutoDetect: true},
+		{name: "openai", provider: "openai", response: `{}`, wantPath: "", wantAutoDetect: true},
+		{name: "explicit path", provider: "gemini", response: `{"path": "text"}`, wantPath: "text", wantAutoDetect: false},
+		{name: "tool_call mode", provider: "gemini", response: `{"mode": "tool_call"}`, wantPath: "", wantAutoDetect: false},
+		{name: "named paths", provider: "gemini", response: `{"paths": {"a": "b"}}`, wantPath: "", wantAutoDetect: false},
+		{name: "text response", provider: "gemini", response: `{"type": "text"}`, wantPath: "", wantAutoDetect: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template := mustLoad(t, fmt.Sprintf(`{"provider": %q, "request": {"url": "https://api.example.com", "body": {}}, "response": %s}`, tt.provider, tt.response))
+			if template.Response.Path != tt.wantPath {
+				t.Errorf("Path = %q, want %q", template.Response.Path, tt.wantPath)
+			}
+			if got := template.Response.AutoDetectEnabled(); got != tt.wantAutoDetect {
+				t.Errorf("AutoDetectEnabled() = %v, want %v", got, tt.wantAutoDetect)
+			}
+		})
+	}
+
+	// Requests other than POST are not completion requests
+	template := mustLoad(t, `{"provider": "gemini", "request": {"url": "https://api.example.com", "method": "GET"}}`)
+	if template.Response.Path != "" {
+		t.Errorf("GET template Path = %q, want empty", template.Response.Path)
+	}
+}
+
+func TestInlineDefaults(t *testing.T) {
+	tests := []struct {
+		name         string
+		content      string
+		replacements map[string]string
+		want         string
+	}{
+		{"provided", "{{model:-gpt-4o}}", map[string]string{"model": "o1"}, "o1"},
+		{"provided empty", "{{model:-gpt-4o}}", map[string]string{"model": ""}, ""},
+		{"fallback", "{{model:-gpt-4o}}", nil, "gpt-4o"},
+		{"empty fallback", "[{{suffix:-}}]", nil, "[]"},
+		{"fallback containing a colon", "{{base:-http://localhost:11434}}/api", nil, "http://localhost:11434/api"},
+		{"fallback containing the separator", "{{a:-b:-c}}", nil, "b:-c"},
+		{"fallback with modifier", "{{q:-a b|urlencode}}", nil, "a+b"},
+		{"missing", "{{prompt}}", nil, "{{prompt}}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := replaceVariablesInString(tt.content, tt.replacements); got != tt.want {
+				t.Errorf("replaceVariablesInString(%q) = %q, want %q", tt.content, got, tt.want)
+			}
+		})
+	}
+
+	template := mustLoad(t, `{
+		"provider": "ollama",
+		"request": {
+			"url": "{{base:-http://localhost:11434}}/api/generate",
+			"body": {"model": "{{model:-llama3}}", "prompt": "{{prompt}}"}
+		}
+	}`)
+	defaults := template.InlineDefaults()
+	if defaults["base"] != "http://localhost:11434" || defaults["model"] != "llama3" || len(defaults) != 2 {
+		t.Errorf("InlineDefaults() = %v", defaults)
+	}
+	if got := template.UnresolvedPlaceholders(nil); len(got) != 1 || got[0] != "{{prompt}}" {
+		t.Errorf("UnresolvedPlaceholders() = %v, want [{{prompt}}]", got)
+	}
+}
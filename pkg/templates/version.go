@@ -0,0 +1,70 @@
+package templates
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseVersion parses a version like "1.4", "v1.4.2" or "v1.4.2-rc1" into its numeric parts
+// Pre-release and build suffixes are ignored
+func parseVersion(version string) ([]int, bool) {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if index := strings.IndexAny(version, "-+"); index >= 0 {
+		version = version[:index]
+	}
+	if version == "" {
+		return nil, false
+	}
+
+	fields := strings.Split(version, ".")
+	parts := make([]int, len(fields))
+	for i, field := range fields {
+		number, err := strconv.Atoi(field)
+		if err != nil || number < 0 {
+			return nil, false
+		}
+		parts[i] = number
+	}
+	return parts, true
+}
+
+// compareVersions returns -1, 0 or 1 as version a is lower than, equal to or higher than b
+// Missing parts count as zero, so 1.4 equals 1.4.0
+func compareVersions(a, b []int) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// Warnings returns the messages to show when the template is used: its deprecation notice and,
+// when the running llm-caller is older than min_tool_version, a request to upgrade
+// Each message follows the template name. Development builds, whose version is not a release number,
+// are never reported as too old
+func (t *Template) Warnings(toolVersion string) []string {
+	var warnings []string
+	if t.Deprecated != "" {
+		warnings = append(warnings, fmt.Sprintf("is deprecated: %s", t.Deprecated))
+	}
+	if t.MinToolVersion != "" {
+		required, ok := parseVersion(t.MinToolVersion)
+		current, isRelease := parseVersion(toolVersion)
+		if ok && isRelease && compareVersions(current, required) < 0 {
+			warnings = append(warnings, fmt.Sprintf("requires llm-caller %s or later, this is %s, please upgrade", t.MinToolVersion, toolVersion))
+		}
+	}
+	return warnings
+}
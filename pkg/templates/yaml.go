@@ -0,0 +1,263 @@
+package templates
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TemplateExtensions are the template file extensions, in lookup order; JSON is the primary format
+var TemplateExtensions = []string{".json", ".yaml", ".yml"}
+
+// IsTemplateFile reports whether the file name has a template extension
+func IsTemplateFile(name string) bool {
+	return templateExt(name) != ""
+}
+
+// TrimTemplateExt removes the template extension from a file name
+func TrimTemplateExt(name string) string {
+	return strings.TrimSuffix(name, templateExt(name))
+}
+
+// templateExt returns the template extension of a file name, or an empty string
+func templateExt(name string) string {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, candidate := range TemplateExtensions {
+		if ext == candidate {
+			return filepath.Ext(name)
+		}
+	}
+	return ""
+}
+
+// CandidateFileNames returns the file names a template name may refer to, in lookup order
+// A name with a template extension is used as given, otherwise each extension is tried
+func CandidateFileNames(name string) []string {
+	if IsTemplateFile(name) {
+		return []string{name}
+	}
+	names := make([]string, 0, len(TemplateExtensions))
+	for _, ext := range TemplateExtensions {
+		names = append(names, name+ext)
+	}
+	return names
+}
+
+// isYAMLFile reports whether a template file is written in YAML
+func isYAMLFile(filePath string) bool {
+	ext := strings.ToLower(filepath.Ext(filePath))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// IsJSONContent reports whether template content is JSON rather than YAML
+// Leading comments are skipped, as JSON templates may start with one
+func IsJSONContent(data string) bool {
+	return nextSignificant([]byte(strings.TrimPrefix(data, string(utf8BOM))), 0) == '{'
+}
+
+// LoadTemplateFromYAML loads a template from a YAML string
+func LoadTemplateFromYAML(yamlStr string) (*Template, error) {
+	if strings.TrimSpace(yamlStr) == "" {
+		return nil, fmt.Errorf("template YAML string is empty")
+	}
+
+	data, err := decodeTemplateText([]byte(yamlStr))
+	if err != nil {
+		return nil, err
+	}
+	data, err = yamlToJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template YAML: %w", err)
+	}
+	return parseTemplate(data, "")
+}
+
+// templateFileJSON returns the JSON form of a template file's content, converting YAML files
+func templateFileJSON(filePath string, data []byte) ([]byte, error) {
+	if !isYAMLFile(filePath) {
+		return data, nil
+	}
+	converted, err := yamlToJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template YAML in %s: %w", filePath, err)
+	}
+	return converted, nil
+}
+
+// yamlToJSON converts a YAML document to JSON so it goes through the same parsing as JSON templates
+// Unquoted scalars of string fields keep their text, e.g. "min_tool_version: 1.10" or "model: 4" in defaults,
+// while free-form fields such as request.body keep their YAML types
+func yamlToJSON(data []byte) ([]byte, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	if len(root.Content) == 0 || resolveAlias(root.Content[0]).Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("template must be a mapping")
+	}
+	value, err := yamlValue(root.Content[0], reflect.TypeOf(Template{}))
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
+
+// rawMessageType is the type of template fields holding arbitrary JSON, e.g. sample_response
+var rawMessageType = reflect.TypeOf(json.RawMessage(nil))
+
+// yamlValue converts a YAML node to a JSON value for a template field of type target
+// A nil target, e.g. an unknown field, keeps the YAML types of the node
+func yamlValue(node *yaml.Node, target reflect.Type) (interface{}, error) {
+	node = resolveAlias(node)
+	for target != nil && target.Kind() == reflect.Pointer {
+		target = target.Elem()
+	}
+	if target == nil || target == rawMessageType || target.Kind() == reflect.Interface {
+		return decodeYAMLNode(node)
+	}
+
+	switch node.Kind {
+	case yaml.ScalarNode:
+		// StringList fields also accept a single string
+		isStringList := target.Kind() == reflect.Slice && target.Elem().Kind() == reflect.String
+		if (target.Kind() == reflect.String || isStringList) && node.Tag != "!!null" {
+			return node.Value, nil
+		}
+
+	case yaml.MappingNode:
+		if target.Kind() != reflect.Struct && target.Kind() != reflect.Map {
+			break
+		}
+		var fields map[string]reflect.StructField
+		if target.Kind() == reflect.Struct {
+			fields = jsonFields(target)
+		}
+		result := make(map[string]interface{})
+		for _, pair := range mappingPairs(node) {
+			key, err := decodeYAMLNode(pair[0])
+			if err != nil {
+				return nil, err
+			}
+			name := fmt.Sprint(key)
+
+			// encoding/json matches struct field names case-insensitively, unknown fields have no type
+			var valueType reflect.Type
+			if target.Kind() == reflect.Map {
+				valueType = target.Elem()
+			} else if field, ok := fields[strings.ToLower(name)]; ok {
+				valueType = field.Type
+			}
+			value, err := yamlValue(pair[1], valueType)
+			if err != nil {
+				return nil, err
+			}
+			result[name] = value
+		}
+		return result, nil
+
+	case yaml.SequenceNode:
+		if target.Kind() != reflect.Slice && target.Kind() != reflect.Array {
+			break
+		}
+		result := make([]interface{}, 0, len(node.Content))
+		for _, item := range node.Content {
+			value, err := yamlValue(item, target.Elem())
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, value)
+		}
+		return result, nil
+	}
+	// Values that do not match the field type are left for the JSON decoder to report
+	return decodeYAMLNode(node)
+}
+
+// decodeYAMLNode decodes a YAML node with its YAML types
+func decodeYAMLNode(node *yaml.Node) (interface{}, error) {
+	var value interface{}
+	if err := node.Decode(&value); err != nil {
+		return nil, err
+	}
+	return normalizeYAML(value), nil
+}
+
+// resolveAlias returns the node an alias (*name) refers to
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	return node
+}
+
+// mappingPairs returns the key and value nodes of a mapping, with the pairs of merge keys (<<: *name) first
+// so that the mapping's own keys override them
+func mappingPairs(node *yaml.Node) [][2]*yaml.Node {
+	var merged, own [][2]*yaml.Node
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if key.Tag != "!!merge" {
+			own = append(own, [2]*yaml.Node{key, value})
+			continue
+		}
+		value = resolveAlias(value)
+		sources := []*yaml.Node{value}
+		if value.Kind == yaml.SequenceNode {
+			sources = value.Content
+		}
+		for _, source := range sources {
+			if source = resolveAlias(source); source.Kind == yaml.MappingNode {
+				merged = append(merged, mappingPairs(source)...)
+			}
+		}
+	}
+	return append(merged, own...)
+}
+
+// jsonFields returns the exported fields of a struct by their lower-case JSON name
+func jsonFields(structType reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[strings.ToLower(name)] = field
+	}
+	return fields
+}
+
+// normalizeYAML converts maps with non-string keys, which JSON cannot encode, to string keyed maps
+func normalizeYAML(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = normalizeYAML(item)
+		}
+		return v
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			result[fmt.Sprint(key)] = normalizeYAML(item)
+		}
+		return result
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalizeYAML(item)
+		}
+		return v
+	default:
+		return v
+	}
+}
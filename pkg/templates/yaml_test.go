@@ -0,0 +1,109 @@
+package templates
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestYAMLScalarsInStringFields(t *testing.T) {
+	template, err := LoadTemplateFromYAML(`
+provider: openai
+title: 2024
+min_tool_version: 1.10
+deprecated: true
+api_key_env: 42
+defaults:
+  model: 4
+  temperature: 0.7
+  empty: null
+request:
+  url: https://api.example.com
+  headers:
+    X-Version: 2
+  body:
+    model: 4
+    temperature: 0.7
+    stream: false
+response:
+  path: choices[0].message.content
+  transform: [trim]
+`)
+	if err != nil {
+		t.Fatalf("LoadTemplateFromYAML: %v", err)
+	}
+
+	if template.Title != "2024" {
+		t.Errorf("Title = %q, want %q", template.Title, "2024")
+	}
+	if template.MinToolVersion != "1.10" {
+		t.Errorf("MinToolVersion = %q, want the unquoted text %q", template.MinToolVersion, "1.10")
+	}
+	if template.Deprecated != "true" {
+		t.Errorf("Deprecated = %q, want %q", template.Deprecated, "true")
+	}
+	if !reflect.DeepEqual([]string(template.APIKeyEnv), []string{"42"}) {
+		t.Errorf("APIKeyEnv = %q, want [42]", template.APIKeyEnv)
+	}
+	wantDefaults := map[string]string{"model": "4", "temperature": "0.7", "empty": ""}
+	if !reflect.DeepEqual(template.Defaults, wantDefaults) {
+		t.Errorf("Defaults = %v, want %v", template.Defaults, wantDefaults)
+	}
+	if got := template.Request.Headers["X-Version"]; got != "2" {
+		t.Errorf("header X-Version = %q, want %q", got, "2")
+	}
+
+	// The free-form body keeps its YAML types
+	wantBody := map[string]interface{}{"model": 4.0, "temperature": 0.7, "stream": false}
+	if !reflect.DeepEqual(template.Request.Body, wantBody) {
+		t.Errorf("Body = %#v, want %#v", template.Request.Body, wantBody)
+	}
+}
+
+func TestYAMLAnchorsAndMergeKeys(t *testing.T) {
+	template, err := LoadTemplateFromYAML(`
+provider: openai
+x-headers: &headers
+  X-Version: 2
+  X-Team: ml
+x-version: &version 3.0
+request:
+  url: https://api.example.com
+  headers:
+    <<: *headers
+    X-Team: search
+  body: {}
+defaults:
+  version: *version
+`)
+	if err != nil {
+		t.Fatalf("LoadTemplateFromYAML: %v", err)
+	}
+	wantHeaders := map[string]string{"X-Version": "2", "X-Team": "search"}
+	if !reflect.DeepEqual(template.Request.Headers, wantHeaders) {
+		t.Errorf("Headers = %v, want %v", template.Request.Headers, wantHeaders)
+	}
+	if template.Defaults["version"] != "3.0" {
+		t.Errorf("Defaults[version] = %q, want %q", template.Defaults["version"], "3.0")
+	}
+}
+
+func TestYAMLTypeErrorNamesField(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want string
+	}{
+		{"quoted integer", "pagination: {next_path: next, cursor_param: cursor, max_pages: \"5\"}", "field pagination.max_pages must be an integer, got string"},
+		{"list for a string", "title: [a, b]", "field title must be a string, got array"},
+		{"string for an object", "defaults: model", "field defaults must be an object, got string"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadTemplateFromYAML("provider: openai\nrequest: {url: https://api.example.com, body: {}}\n" + tt.yaml + "\n")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("LoadTemplateFromYAML error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
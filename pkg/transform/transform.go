@@ -0,0 +1,151 @@
+package transform
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Transform names supported in a template's response.transform list
+const (
+	Trim               = "trim"
+	JSONPretty         = "json_pretty"
+	ExtractCode        = "extract_code"
+	CollapseWhitespace = "collapse_whitespace"
+	Dedent             = "dedent"
+)
+
+// transformFunc converts extracted response content
+type transformFunc func(content string) (string, error)
+
+var transforms = map[string]transformFunc{
+	Trim:               trim,
+	JSONPretty:         jsonPretty,
+	ExtractCode:        extractCode,
+	CollapseWhitespace: collapseWhitespace,
+	Dedent:             dedent,
+}
+
+// Names returns the supported transform names in sorted order
+func Names() []string {
+	names := make([]string, 0, len(transforms))
+	for name := range transforms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Validate checks that every transform name is supported
+func Validate(names []string) error {
+	for _, name := range names {
+		if _, ok := transforms[name]; !ok {
+			return fmt.Errorf("unknown transform '%s', supported transforms: %s", name, strings.Join(Names(), ", "))
+		}
+	}
+	return nil
+}
+
+// Apply runs the transforms in order on the content
+func Apply(content string, names []string) (string, error) {
+	for _, name := range names {
+		fn, ok := transforms[name]
+		if !ok {
+			return "", fmt.Errorf("unknown transform '%s', supported transforms: %s", name, strings.Join(Names(), ", "))
+		}
+
+		var err error
+		content, err = fn(content)
+		if err != nil {
+			return "", fmt.Errorf("transform '%s' failed: %w", name, err)
+		}
+	}
+	return content, nil
+}
+
+// trim removes leading and trailing whitespace
+func trim(content string) (string, error) {
+	return strings.TrimSpace(content), nil
+}
+
+// jsonPretty reformats JSON content with two-space indentation
+func jsonPretty(content string) (string, error) {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(strings.TrimSpace(content)), "", "  "); err != nil {
+		return "", fmt.Errorf("content is not valid JSON: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// extractCode returns the contents of the first ``` fenced code block
+// Content without a fenced block is returned unchanged
+func extractCode(content string) (string, error) {
+	lines := strings.Split(content, "\n")
+	start := -1
+	for i, line := range lines {
+		if !strings.HasPrefix(strings.TrimSpace(line), "```") {
+			continue
+		}
+		if start < 0 {
+			start = i
+			continue
+		}
+		return strings.Join(lines[start+1:i], "\n"), nil
+	}
+	return content, nil
+}
+
+// collapseWhitespace reduces each run of blank lines to a single empty line
+// Lines holding only spaces or tabs count as blank
+func collapseWhitespace(content string) (string, error) {
+	lines := strings.Split(content, "\n")
+	result := make([]string, 0, len(lines))
+	previousBlank := false
+	for _, line := range lines {
+		blank := strings.TrimSpace(line) == ""
+		if blank && previousBlank {
+			continue
+		}
+		if blank {
+			line = ""
+		}
+		result = append(result, line)
+		previousBlank = blank
+	}
+	return strings.Join(result, "\n"), nil
+}
+
+// dedent removes the leading spaces and tabs that all non-blank lines have in common
+// Blank lines are ignored when finding the common indentation and emptied
+func dedent(content string) (string, error) {
+	lines := strings.Split(content, "\n")
+	var indent string
+	found := false
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		lineIndent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if !found {
+			indent, found = lineIndent, true
+			continue
+		}
+		// Keep the longest prefix shared with this line's indentation
+		i := 0
+		for i < len(indent) && i < len(lineIndent) && indent[i] == lineIndent[i] {
+			i++
+		}
+		indent = indent[:i]
+	}
+
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			lines[i] = ""
+			continue
+		}
+		lines[i] = strings.TrimPrefix(line, indent)
+	}
+	return strings.Join(lines, "\n"), nil
+}
@@ -0,0 +1,77 @@
+package transform
+
+import "testing"
+
+func TestCollapseWhitespace(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"no blank lines", "a\nb", "a\nb"},
+		{"single blank line", "a\n\nb", "a\n\nb"},
+		{"run of blank lines", "a\n\n\n\nb", "a\n\nb"},
+		{"whitespace-only lines", "a\n  \n\t\n \t \nb", "a\n\nb"},
+		{"leading and trailing runs", "\n\n\na\n\n\n", "\na\n"},
+		{"several runs", "# Title\n\n\n\nIntro\n\n\n- one\n- two\n\n\n\nEnd", "# Title\n\nIntro\n\n- one\n- two\n\nEnd"},
+		{"indentation kept", "  a\n\n\n    b", "  a\n\n    b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Apply(tt.content, []string{CollapseWhitespace})
+			if err != nil {
+				t.Fatalf("Apply: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("collapse_whitespace(%q) = %q, want %q", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDedent(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"no indentation", "a\n  b", "a\n  b"},
+		{"common spaces", "    a\n      b\n    c", "a\n  b\nc"},
+		{"common tabs", "\t\ta\n\t\t\tb", "a\n\tb"},
+		{"blank lines ignored and emptied", "    a\n\n  \n    b", "a\n\n\nb"},
+		{"tab and spaces mixed in every line", "\t  a\n\t    b", "a\n  b"},
+		{"tab and spaces differ", "\ta\n    b", "\ta\n    b"},
+		{"shared prefix of mixed indentation", "  \ta\n  b", "\ta\nb"},
+		{"code block", "    func main() {\n        fmt.Println(1)\n    }\n", "func main() {\n    fmt.Println(1)\n}\n"},
+		{"only blank lines", "  \n\t\n", "\n\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Apply(tt.content, []string{Dedent})
+			if err != nil {
+				t.Fatalf("Apply: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("dedent(%q) = %q, want %q", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCollapseWhitespaceThenDedent(t *testing.T) {
+	content := "\n    Answer:\n\n\n\n      - one\n    \n\n    Done\n"
+	want := "\nAnswer:\n\n  - one\n\nDone\n"
+	got, err := Apply(content, []string{CollapseWhitespace, Dedent})
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if got != want {
+		t.Errorf("Apply = %q, want %q", got, want)
+	}
+}
+
+func TestValidateUnknownTransform(t *testing.T) {
+	if err := Validate([]string{Trim, "collapse"}); err == nil {
+		t.Error("Validate accepted an unknown transform")
+	}
+}
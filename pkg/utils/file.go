@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// WriteFileAtomic replaces the content of a file so that it holds either its old or its complete new content
+// The data is written to a temporary file in the same directory and renamed over the target. An existing
+// file keeps its permissions, and a symbolic link keeps pointing at the file it names, which is replaced
+func WriteFileAtomic(path string, data []byte) error {
+	perm := GetFilePermissions()
+	if target, err := filepath.EvalSymlinks(path); err == nil {
+		path = target
+		if info, err := os.Stat(path); err == nil {
+			perm = info.Mode().Perm()
+		}
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*.tmp")
+	if err != nil {
+		// Report the target rather than the generated temporary name, e.g. for a missing directory
+		var pathErr *os.PathError
+		if errors.As(err, &pathErr) {
+			return &os.PathError{Op: "open", Path: path, Err: pathErr.Err}
+		}
+		return err
+	}
+	tmpPath := file.Name()
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
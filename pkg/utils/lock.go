@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	// lockSuffix is appended to a file path to name its lock file
+	lockSuffix = ".lock"
+	// lockTimeout is how long LockFile waits for another process to release the lock
+	lockTimeout = 10 * time.Second
+	// lockRetryInterval is the delay between attempts to acquire the lock
+	lockRetryInterval = 20 * time.Millisecond
+	// staleLockAge is the age after which a lock file left by a crashed process is removed
+	staleLockAge = 30 * time.Second
+)
+
+// LockFile acquires the lock file of path, waiting for other holders up to lockTimeout
+// The lock is a file created exclusively next to path, so concurrent processes serialize; call the returned function to release it
+func LockFile(path string) (func(), error) {
+	lockPath := path + lockSuffix
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, GetFilePermissions())
+		if err == nil {
+			fmt.Fprintf(file, "%d\n", os.Getpid())
+			file.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("failed to create lock file: %w", err)
+		}
+
+		// A lock older than staleLockAge was left behind by a process that did not finish
+		if info, statErr := os.Stat(lockPath); statErr == nil && time.Since(info.ModTime()) > staleLockAge {
+			os.Remove(lockPath)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for lock %s, remove it if no other llm-caller is running", lockPath)
+		}
+		time.Sleep(lockRetryInterval)
+	}
+}
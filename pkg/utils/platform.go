@@ -1,9 +1,12 @@
 package utils
 
 import (
+	"context"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strings"
 )
 
 // GetUserConfigDir returns the user configuration directory path
@@ -35,3 +38,19 @@ func GetDirPermissions() os.FileMode {
 func CreateDirWithPlatformPermissions(dirname string) error {
 	return os.MkdirAll(dirname, GetDirPermissions())
 }
+
+// ShellCommand creates a command that runs a command line through the platform shell
+func ShellCommand(ctx context.Context, commandLine string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", commandLine)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", commandLine)
+}
+
+// ShellQuote quotes an argument for a POSIX shell unless it only contains safe characters
+func ShellQuote(arg string) string {
+	if arg != "" && strings.Trim(arg, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./:=@,+%") == "" {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// ProxyFunc returns the proxy selection function for an HTTP transport.
+// An empty proxyURL falls back to HTTP_PROXY/HTTPS_PROXY/NO_PROXY from the environment,
+// otherwise the explicit proxy URL is validated and used for every request
+func ProxyFunc(proxyURL string) (func(*http.Request) (*url.URL, error), error) {
+	if proxyURL == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+
+	parsed, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL '%s': %w", proxyURL, err)
+	}
+
+	switch parsed.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("invalid proxy URL '%s': scheme must be http, https, or socks5", proxyURL)
+	}
+	if parsed.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL '%s': missing host", proxyURL)
+	}
+
+	return http.ProxyURL(parsed), nil
+}
+
+// NewHTTPTransport creates an HTTP transport using the given proxy configuration
+func NewHTTPTransport(proxyURL string) (*http.Transport, error) {
+	proxy, err := ProxyFunc(proxyURL)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxy
+	return transport, nil
+}
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"encoding/base64"
+	"net/url"
+	"strings"
+)
+
+// MaskSecret masks a secret for display, keeping only the first and last 4 characters
+// Secrets too short to partially reveal are masked completely
+func MaskSecret(secret string) string {
+	if len(secret) <= 12 {
+		return strings.Repeat("*", len(secret))
+	}
+	return secret[:4] + strings.Repeat("*", len(secret)-8) + secret[len(secret)-4:]
+}
+
+// RedactedPlaceholder replaces secrets in redacted values
+const RedactedPlaceholder = "[REDACTED]"
+
+// Redact removes every secret from value, including the Base64 form used by basic auth and the URL-encoded form
+func Redact(value string, secrets ...string) string {
+	for _, secret := range secrets {
+		if secret == "" {
+			continue
+		}
+		value = strings.ReplaceAll(value, base64.StdEncoding.EncodeToString([]byte(secret)), RedactedPlaceholder)
+		value = strings.ReplaceAll(value, secret, RedactedPlaceholder)
+		if escaped := url.QueryEscape(secret); escaped != secret {
+			value = strings.ReplaceAll(value, escaped, RedactedPlaceholder)
+		}
+	}
+	return value
+}
+
+// redactedError carries an error message with secrets removed, the original error can still be unwrapped
+type redactedError struct {
+	message string
+	err     error
+}
+
+func (e *redactedError) Error() string {
+	return e.message
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// RedactError returns err with every secret removed from its message
+func RedactError(err error, secrets ...string) error {
+	if err == nil {
+		return nil
+	}
+	message := Redact(err.Error(), secrets...)
+	if message == err.Error() {
+		return err
+	}
+	return &redactedError{message: message, err: err}
+}